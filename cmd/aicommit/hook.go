@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SimonGino/aicommit/internal/git"
+	"github.com/SimonGino/aicommit/internal/hook"
+	"github.com/urfave/cli/v2"
+)
+
+// hookTimeout 钩子中生成提交消息的最长时间，超时后照常打开编辑器
+const hookTimeout = 2 * time.Minute
+
+// repoHooksDir 返回当前仓库的钩子目录，遵循 core.hooksPath
+func repoHooksDir() (string, error) {
+	repo, err := git.GetRepo("")
+	if err != nil {
+		return "", fmt.Errorf("获取Git仓库失败: %w", err)
+	}
+	return repo.HooksDir()
+}
+
+func hookInstallAction(c *cli.Context) error {
+	dir, err := repoHooksDir()
+	if err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取 aicommit 路径失败: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	status, err := hook.Install(dir, executable)
+	if err != nil {
+		return fmt.Errorf("安装钩子失败: %w", err)
+	}
+	fmt.Printf("✓ 已安装钩子: %s\n", status.Path)
+	if status.Chained != "" {
+		fmt.Printf("  原有的钩子已改名为 %s，会先于 aicommit 执行\n", filepath.Base(status.Chained))
+	}
+	fmt.Println("  之后直接使用 git commit 时会自动生成提交消息（使用 -m、--amend 或合并时除外）")
+	return nil
+}
+
+func hookUninstallAction(c *cli.Context) error {
+	dir, err := repoHooksDir()
+	if err != nil {
+		return err
+	}
+	restored, err := hook.Uninstall(dir)
+	if err != nil {
+		return fmt.Errorf("卸载钩子失败: %w", err)
+	}
+	fmt.Println("✓ 已删除 aicommit 钩子")
+	if restored != "" {
+		fmt.Printf("  已恢复原有的钩子: %s\n", restored)
+	}
+	return nil
+}
+
+func hookStatusAction(c *cli.Context) error {
+	dir, err := repoHooksDir()
+	if err != nil {
+		return err
+	}
+	status, err := hook.Check(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("钩子目录: %s\n", dir)
+	switch {
+	case status.Installed:
+		fmt.Printf("\033[32m✓\033[0m 已安装: %s\n", status.Path)
+		if status.Chained != "" {
+			fmt.Printf("  先执行原有的钩子: %s\n", status.Chained)
+		}
+	case status.Foreign:
+		fmt.Printf("\033[33m⚠\033[0m 已有其他 %s 钩子，安装时会保留并先执行它\n", hook.Name)
+	default:
+		fmt.Println("✗ 未安装，使用 'aicommit hook install' 安装")
+	}
+	return nil
+}
+
+// hookRunAction 由 prepare-commit-msg 钩子调用，参数依次为提交消息文件、来源和提交
+// 只在没有指定来源（即普通的 git commit）时生成；合并、--amend、-m、-c 等情况不做处理
+// 生成失败时只打印警告，不阻止提交
+func hookRunAction(c *cli.Context) error {
+	msgFile := c.Args().Get(0)
+	if msgFile == "" {
+		return fmt.Errorf("缺少提交消息文件参数")
+	}
+	if source := c.Args().Get(1); source != "" {
+		return nil
+	}
+	// git 把钩子的标准输出显示为提交的输出，提示信息统一写到标准错误
+	progress = os.Stderr
+
+	if err := fillMessageFile(c, msgFile); err != nil {
+		fmt.Fprintf(os.Stderr, "\033[33m⚠ aicommit 未能生成提交消息: %v\033[0m\n", err)
+	}
+	return nil
+}
+
+// fillMessageFile 根据暂存区生成提交消息，写在提交消息文件中 git 生成的注释之前
+func fillMessageFile(c *cli.Context, msgFile string) error {
+	data, err := os.ReadFile(msgFile)
+	if err != nil {
+		return fmt.Errorf("读取提交消息文件失败: %w", err)
+	}
+
+	repo, err := git.GetRepo("")
+	if err != nil {
+		return fmt.Errorf("获取Git仓库失败: %w", err)
+	}
+	// 提交模板等已经提供了内容时不覆盖
+	if git.CleanMessage(string(data), repo.CommentChar()) != "" {
+		return nil
+	}
+
+	staged, err := repo.GetStagedChanges()
+	if err != nil {
+		return fmt.Errorf("获取已暂存更改失败: %w", err)
+	}
+	if len(staged) == 0 {
+		return nil
+	}
+
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+	commitInfo, err := stagedCommitInfo(cfg, repo, staged)
+	if err != nil {
+		return err
+	}
+	aiProvider, err := newAIProvider(cfg, cfg.Language)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	message, err := aiProvider.GenerateCommitMessage(ctx, commitInfo)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(msgFile, []byte(message.Text()+"\n"+string(data)), 0644); err != nil {
+		return fmt.Errorf("写入提交消息文件失败: %w", err)
+	}
+	for _, w := range message.Warnings {
+		fmt.Fprintf(os.Stderr, "⚠ %s\n", w)
+	}
+	fmt.Fprintf(os.Stderr, "✓ aicommit 已生成提交消息: %s\n", message.Title)
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/SimonGino/aicommit/internal/git"
+	"github.com/SimonGino/aicommit/internal/lint"
+	"github.com/urfave/cli/v2"
+)
+
+// lintResult 一条提交信息的检查结果
+type lintResult struct {
+	// Commit 提交哈希，检查文件或标准输入时为空
+	Commit  string `json:"commit,omitempty"`
+	Subject string `json:"subject"`
+	// Skipped 合并、回滚等 git 自动生成的提交信息不做检查
+	Skipped bool         `json:"skipped,omitempty"`
+	Issues  []lint.Issue `json:"issues"`
+}
+
+// lintReport lint 命令的 JSON 输出
+type lintReport struct {
+	Valid   bool         `json:"valid"`
+	Results []lintResult `json:"results"`
+}
+
+func lintAction(c *cli.Context) error {
+	asJSON, err := jsonOutput(c)
+	if err != nil {
+		return err
+	}
+	revRange := c.String("range")
+	if c.NArg() > 1 || (revRange != "" && c.NArg() > 0) {
+		return fmt.Errorf("只能指定一个提交信息文件或 --range 之一")
+	}
+
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+	rules := cfg.LintRules()
+
+	results := []lintResult{}
+	if revRange != "" {
+		repo, err := git.GetRepo("")
+		if err != nil {
+			return fmt.Errorf("获取Git仓库失败: %w", err)
+		}
+		entries, err := repo.GetCommitMessages(revRange)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			result := lintMessage(entry.Message, rules)
+			result.Commit = entry.Hash
+			results = append(results, result)
+		}
+	} else {
+		message, err := readMessageFile(c.Args().First())
+		if err != nil {
+			return err
+		}
+		results = append(results, lintMessage(message, rules))
+	}
+
+	valid := true
+	for _, result := range results {
+		if lint.HasErrors(result.Issues) {
+			valid = false
+		}
+	}
+
+	if asJSON {
+		if err := printJSON(lintReport{Valid: valid, Results: results}); err != nil {
+			return err
+		}
+	} else {
+		printLintResults(results, revRange != "")
+	}
+
+	if !valid {
+		// 结果已经输出，只需要以非零状态退出
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// readMessageFile 读取提交信息文件（commit-msg 钩子的参数），path 为空或 "-" 时读取标准输入
+// 按 git 的规则去掉注释行和 "git commit -v" 附加的 diff
+func readMessageFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "" || path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取提交信息失败: %w", err)
+	}
+
+	commentChar := "#"
+	if repo, err := git.GetRepo(""); err == nil {
+		commentChar = repo.CommentChar()
+	}
+	return git.CleanMessage(string(data), commentChar), nil
+}
+
+// lintMessage 检查一条提交信息，git 自动生成的提交信息标记为跳过
+func lintMessage(message string, rules lint.Rules) lintResult {
+	subject, _, _ := strings.Cut(message, "\n")
+	result := lintResult{Subject: subject, Issues: []lint.Issue{}}
+	if lint.Ignored(message) {
+		result.Skipped = true
+		return result
+	}
+	result.Issues = lint.Check(message, rules)
+	if result.Issues == nil {
+		result.Issues = []lint.Issue{}
+	}
+	return result
+}
+
+// printLintResults 打印检查结果，检查多个提交时逐条列出并在最后汇总
+func printLintResults(results []lintResult, showCommits bool) {
+	if len(results) == 0 {
+		fmt.Println("没有需要检查的提交")
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		hasErrors := lint.HasErrors(result.Issues)
+		if hasErrors {
+			failed++
+		}
+
+		if showCommits {
+			status := "\033[32m✓\033[0m"
+			switch {
+			case result.Skipped:
+				status = "\033[90m-\033[0m"
+			case hasErrors:
+				status = "\033[31m✗\033[0m"
+			case len(result.Issues) > 0:
+				status = "\033[33m⚠\033[0m"
+			}
+			fmt.Printf("%s %s %s\n", status, shortHash(result.Commit), result.Subject)
+		} else if hasErrors {
+			fmt.Println("\033[31m✗ 提交信息不符合 Conventional Commits 规范:\033[0m")
+		}
+
+		for _, issue := range result.Issues {
+			color := "\033[31m"
+			if issue.Severity == lint.SeverityWarning {
+				color = "\033[33m"
+			}
+			fmt.Printf("    %s%s\033[0m\n", color, issue)
+		}
+	}
+
+	if showCommits {
+		fmt.Printf("\n检查了 %d 个提交，%d 个不符合规范\n", len(results), failed)
+	}
+}
+
+// shortHash 返回提交哈希的前 7 位
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
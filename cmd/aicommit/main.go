@@ -2,16 +2,23 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/SimonGino/aicommit/internal/ai"
 	"github.com/SimonGino/aicommit/internal/config"
 	"github.com/SimonGino/aicommit/internal/git"
+	"github.com/SimonGino/aicommit/internal/ignore"
 	"github.com/SimonGino/aicommit/internal/interactive"
+	"github.com/SimonGino/aicommit/internal/lint"
+	"github.com/SimonGino/aicommit/internal/scan"
+	"github.com/SimonGino/aicommit/internal/secret"
 	"github.com/urfave/cli/v2"
 )
 
@@ -36,7 +43,7 @@ func main() {
 					&cli.StringFlag{
 						Name:    "api-key",
 						Aliases: []string{"k"},
-						Usage:   "OpenAI API密钥",
+						Usage:   "API密钥",
 					},
 					&cli.StringFlag{
 						Name:    "base-url",
@@ -56,14 +63,74 @@ func main() {
 					&cli.StringFlag{
 						Name:    "provider",
 						Aliases: []string{"p"},
-						Usage:   "AI提供商 (openai, azure)",
+						Usage:   fmt.Sprintf("AI提供商 (%s)", strings.Join(ai.Providers(), ", ")),
 					},
 					&cli.StringFlag{
 						Name:  "azure-api-version",
 						Usage: "Azure OpenAI API版本 (默认: 2024-02-15-preview)",
 					},
+					&cli.IntFlag{
+						Name:  "max-retries",
+						Usage: "遇到限流或服务端错误时的最大重试次数，0 表示不重试 (默认: 3)",
+					},
+					&cli.StringFlag{
+						Name:  "retry-base-delay",
+						Usage: "首次重试的等待时间，之后按指数增长 (默认: 1s)",
+					},
+					&cli.StringFlag{
+						Name:  "fallback",
+						Usage: "配置指定名称的备用提供商（与 --provider、--api-key 等参数一起使用）",
+					},
+					&cli.StringFlag{
+						Name:  "remove-fallback",
+						Usage: "删除指定名称的备用提供商",
+					},
+					&cli.StringFlag{
+						Name:  "profile",
+						Usage: "修改指定的配置档案（不存在时自动创建）",
+					},
+					&cli.StringFlag{
+						Name:  "secret-backend",
+						Usage: "API密钥的存储方式 (keyring: 系统钥匙串, file: 口令加密文件, plain: 明文保存在配置文件)",
+					},
+					&cli.StringFlag{
+						Name:  "diff-mode",
+						Usage: "diff 超出预算时的处理方式 (truncate: 截断, summarize: 分块总结后生成)",
+					},
+					&cli.IntFlag{
+						Name:  "diff-budget",
+						Usage: "单次请求中 diff 的最大 token 数，0 表示按模型的上下文窗口计算 (默认: 0)",
+					},
+					&cli.IntFlag{
+						Name:  "summary-concurrency",
+						Usage: fmt.Sprintf("分块总结时的最大并发请求数 (默认: %d)", ai.DefaultSummaryConcurrency),
+					},
+					&cli.StringFlag{
+						Name:  "secret-scan",
+						Usage: "diff 中检测到密钥时的处理方式 (redact: 替换为占位符, abort: 中止, off: 不检测)",
+					},
+					&cli.StringFlag{
+						Name:  "secret-allow",
+						Usage: "添加密钥检测白名单：匹配密钥的正则表达式，或 path:<glob> 跳过指定文件",
+					},
+					&cli.StringFlag{
+						Name:  "remove-secret-allow",
+						Usage: "删除密钥检测白名单条目",
+					},
+					&cli.StringFlag{
+						Name:  "commit-types",
+						Usage: "允许的提交类型，以逗号分隔，生成和 lint 检查都使用这些类型（空字符串恢复默认）",
+					},
 				},
 				Action: configAction,
+				Subcommands: []*cli.Command{
+					{
+						Name:      "use",
+						Usage:     "设置默认使用的配置档案，不带参数时列出所有档案",
+						ArgsUsage: "[name]",
+						Action:    configUseAction,
+					},
+				},
 			},
 			{
 				Name:    "report",
@@ -95,14 +162,134 @@ func main() {
 						Name:  "author",
 						Usage: "指定作者邮箱 (默认使用当前Git配置)",
 					},
+					outputFlag(),
+					profileFlag(),
 				},
 				Action: reportAction,
 			},
 			{
 				Name:   "check",
 				Usage:  "检查配置和 API 连通性",
+				Flags:  []cli.Flag{outputFlag(), profileFlag()},
 				Action: checkAction,
 			},
+			{
+				Name:  "hook",
+				Usage: "管理 prepare-commit-msg 钩子，直接使用 git commit 时自动生成提交消息",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "install",
+						Usage:  "安装钩子，已有的钩子会先于 aicommit 执行",
+						Action: hookInstallAction,
+					},
+					{
+						Name:   "uninstall",
+						Usage:  "删除钩子并恢复安装前已有的钩子",
+						Action: hookUninstallAction,
+					},
+					{
+						Name:   "status",
+						Usage:  "查看钩子的安装状态",
+						Action: hookStatusAction,
+					},
+					{
+						Name:      "run",
+						Usage:     "由钩子调用，为 git commit 填写提交消息",
+						ArgsUsage: "<提交消息文件> [来源] [提交]",
+						Hidden:    true,
+						Action:    hookRunAction,
+					},
+				},
+			},
+			{
+				Name:      "lint",
+				Usage:     "按 Conventional Commits 规范检查提交信息，可作为 commit-msg 钩子或在 CI 中使用",
+				ArgsUsage: "[提交信息文件，默认读取标准输入]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "range",
+						Usage: "检查指定范围内的所有提交，如 origin/main..HEAD",
+					},
+					outputFlag(),
+					profileFlag(),
+				},
+				Action: lintAction,
+			},
+			{
+				Name:      "reword",
+				Usage:     "根据提交自身的 diff 重新生成较早提交的提交信息，并通过 rebase 改写",
+				ArgsUsage: "<提交>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "language",
+						Aliases: []string{"l"},
+						Usage:   "指定输出语言 (en, zh-CN, zh-TW)",
+					},
+					&cli.StringFlag{
+						Name:  "diff-mode",
+						Usage: "diff 超出预算时的处理方式 (truncate, summarize)",
+					},
+					&cli.StringFlag{
+						Name:  "secret-scan",
+						Usage: "检测到密钥时的处理方式 (redact, abort, off)",
+					},
+					&cli.IntFlag{
+						Name:  "candidates",
+						Value: 1,
+						Usage: fmt.Sprintf("一次生成的候选提交消息数量 (1-%d)", interactive.MaxCandidates),
+					},
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "不确认，直接使用生成的提交消息",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "允许改写已推送到远程的提交",
+					},
+					profileFlag(),
+				},
+				Action: rewordAction,
+			},
+			{
+				Name:      "rewrite-range",
+				Usage:     "为范围内的每个提交根据其自身的 diff 重新生成提交信息，确认后一次性改写",
+				ArgsUsage: "<范围，如 origin/main..HEAD>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "language",
+						Aliases: []string{"l"},
+						Usage:   "指定输出语言 (en, zh-CN, zh-TW)",
+					},
+					&cli.StringFlag{
+						Name:  "diff-mode",
+						Usage: "diff 超出预算时的处理方式 (truncate, summarize)",
+					},
+					&cli.StringFlag{
+						Name:  "secret-scan",
+						Usage: "检测到密钥时的处理方式 (redact, abort, off)",
+					},
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "不确认，直接改写",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "只显示新旧提交信息对照表，不改写",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "允许改写已推送到远程的提交；与 --undo 一起使用时允许丢弃改写之后的新提交",
+					},
+					&cli.BoolFlag{
+						Name:  "undo",
+						Usage: "恢复到最近一次改写之前的历史",
+					},
+					profileFlag(),
+				},
+				Action: rewriteRangeAction,
+			},
 		},
 		Flags: []cli.Flag{
 			&cli.StringFlag{
@@ -115,6 +302,42 @@ func main() {
 				Aliases: []string{"l"},
 				Usage:   "指定输出语言 (en, zh-CN, zh-TW)",
 			},
+			&cli.StringFlag{
+				Name:  "diff-mode",
+				Usage: "本次提交 diff 超出预算时的处理方式 (truncate, summarize)",
+			},
+			&cli.StringFlag{
+				Name:  "secret-scan",
+				Usage: "本次提交检测到密钥时的处理方式 (redact, abort, off)",
+			},
+			&cli.IntFlag{
+				Name:  "candidates",
+				Value: 1,
+				Usage: fmt.Sprintf("一次生成的候选提交消息数量 (1-%d)", interactive.MaxCandidates),
+			},
+			&cli.StringFlag{
+				Name:  "stage",
+				Usage: "不交互选择文件，按指定方式暂存 (none: 使用当前暂存区, all: 暂存所有变更, tracked: 只暂存已跟踪文件的修改)",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "不确认，直接使用生成的提交消息提交",
+			},
+			&cli.BoolFlag{
+				Name:  "amend",
+				Usage: "重新生成 HEAD 的提交消息并修改该提交，已暂存的更改一并加入",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "允许使用 --amend 改写已推送到远程的提交",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "只输出生成的提交消息，不暂存也不提交（进度信息输出到标准错误）",
+			},
+			outputFlag(),
+			profileFlag(),
 		},
 		Action: defaultAction,
 	}
@@ -124,8 +347,86 @@ func main() {
 	}
 }
 
+// profileFlag 选择配置档案的参数
+func profileFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "profile",
+		Usage: "使用指定的配置档案 (默认使用 'aicommit config use' 设置的档案)",
+	}
+}
+
+// outputFlag 选择输出格式的参数
+func outputFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "output",
+		Value: "text",
+		Usage: "输出格式 (text, json)",
+	}
+}
+
+// jsonOutput 判断是否以 JSON 输出结果，格式无效时返回错误
+func jsonOutput(c *cli.Context) (bool, error) {
+	switch output := c.String("output"); output {
+	case "text":
+		return false, nil
+	case "json":
+		return true, nil
+	default:
+		return false, fmt.Errorf("不支持的输出格式: %s，请使用 text 或 json", output)
+	}
+}
+
+// printJSON 以缩进格式把结果输出到标准输出
+func printJSON(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化输出失败: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// progress 进度和提示信息的输出位置，--dry-run 等需要解析标准输出的场景下改为标准错误
+var progress io.Writer = os.Stdout
+
+// loadConfig 加载 --profile 指定的配置档案，未指定时使用当前档案
+func loadConfig(c *cli.Context) (*config.Config, error) {
+	return config.LoadProfile(c.String("profile"))
+}
+
 func configAction(c *cli.Context) error {
-	cfg := config.LoadConfig()
+	// 只修改全局配置，不合并仓库配置
+	cfg, err := config.OpenProfile(c.String("profile"))
+	if err != nil {
+		return err
+	}
+	if cfg.Profile() != config.DefaultProfile {
+		fmt.Printf("配置档案: %s\n", cfg.Profile())
+	}
+
+	if name := c.String("remove-fallback"); name != "" {
+		if err := cfg.RemoveFallback(name); err != nil {
+			return fmt.Errorf("删除备用提供商失败: %w", err)
+		}
+		fmt.Printf("✓ 已删除备用提供商: %s\n", name)
+		fmt.Printf("配置文件: %s\n", cfg.ConfigFile())
+		return nil
+	}
+
+	if name := c.String("fallback"); name != "" {
+		return configureFallback(c, cfg, name)
+	}
+
+	if backend := c.String("secret-backend"); backend != "" {
+		b, err := secret.ParseBackend(backend)
+		if err != nil {
+			return err
+		}
+		if err := config.SetSecretBackend(b); err != nil {
+			return fmt.Errorf("配置密钥存储失败: %w", err)
+		}
+		fmt.Printf("✓ API 密钥将保存在: %s\n", b.Label())
+	}
 
 	if apiKey := c.String("api-key"); apiKey != "" {
 		if err := cfg.UpdateAPIKey(apiKey); err != nil {
@@ -169,11 +470,177 @@ func configAction(c *cli.Context) error {
 		fmt.Printf("✓ 成功配置 Azure API 版本: %s\n", azureAPIVersion)
 	}
 
+	if c.IsSet("max-retries") {
+		maxRetries := c.Int("max-retries")
+		if err := cfg.UpdateMaxRetries(maxRetries); err != nil {
+			return fmt.Errorf("配置重试次数失败: %w", err)
+		}
+		fmt.Printf("✓ 成功配置最大重试次数: %d\n", maxRetries)
+	}
+
+	if retryBaseDelay := c.String("retry-base-delay"); retryBaseDelay != "" {
+		if err := cfg.UpdateRetryBaseDelay(retryBaseDelay); err != nil {
+			return fmt.Errorf("配置重试等待时间失败: %w", err)
+		}
+		fmt.Printf("✓ 成功配置重试等待时间: %s\n", retryBaseDelay)
+	}
+
+	if mode := c.String("diff-mode"); mode != "" {
+		if err := cfg.UpdateDiffMode(mode); err != nil {
+			return fmt.Errorf("配置 diff 处理方式失败: %w", err)
+		}
+		fmt.Printf("✓ 成功配置 diff 处理方式: %s\n", mode)
+	}
+
+	if c.IsSet("diff-budget") {
+		budget := c.Int("diff-budget")
+		if err := cfg.UpdateDiffBudget(budget); err != nil {
+			return fmt.Errorf("配置 diff 预算失败: %w", err)
+		}
+		fmt.Printf("✓ 成功配置 diff 预算: %d\n", budget)
+	}
+
+	if c.IsSet("summary-concurrency") {
+		concurrency := c.Int("summary-concurrency")
+		if err := cfg.UpdateSummaryConcurrency(concurrency); err != nil {
+			return fmt.Errorf("配置并发数失败: %w", err)
+		}
+		fmt.Printf("✓ 成功配置分块总结并发数: %d\n", concurrency)
+	}
+
+	if mode := c.String("secret-scan"); mode != "" {
+		if err := cfg.UpdateSecretScan(mode); err != nil {
+			return fmt.Errorf("配置密钥检测失败: %w", err)
+		}
+		fmt.Printf("✓ 成功配置密钥检测模式: %s\n", mode)
+	}
+
+	if entry := c.String("secret-allow"); entry != "" {
+		if err := cfg.AddSecretAllow(entry); err != nil {
+			return fmt.Errorf("添加密钥检测白名单失败: %w", err)
+		}
+		fmt.Printf("✓ 已添加密钥检测白名单: %s\n", entry)
+	}
+
+	if entry := c.String("remove-secret-allow"); entry != "" {
+		if err := cfg.RemoveSecretAllow(entry); err != nil {
+			return fmt.Errorf("删除密钥检测白名单失败: %w", err)
+		}
+		fmt.Printf("✓ 已删除密钥检测白名单: %s\n", entry)
+	}
+
+	if c.IsSet("commit-types") {
+		var types []string
+		for _, t := range strings.Split(c.String("commit-types"), ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+		if err := cfg.UpdateCommitTypes(types); err != nil {
+			return fmt.Errorf("配置提交类型失败: %w", err)
+		}
+		fmt.Printf("✓ 成功配置提交类型: %s\n", strings.Join(cfg.CommitTypeNames(), ", "))
+	}
+
+	fmt.Printf("配置文件: %s\n", cfg.ConfigFile())
+	return nil
+}
+
+// configureFallback 添加或更新备用提供商，--provider 等参数作用于该条目而非主提供商
+func configureFallback(c *cli.Context, cfg *config.Config, name string) error {
+	entry, exists := cfg.Fallback(name)
+	if !exists {
+		entry.Name = name
+	}
+
+	if provider := c.String("provider"); provider != "" {
+		entry.Provider = provider
+	}
+	if entry.Provider == "" {
+		return fmt.Errorf("新增备用提供商需要指定 --provider (%s)", strings.Join(ai.Providers(), ", "))
+	}
+	if apiKey := c.String("api-key"); apiKey != "" {
+		entry.APIKey = apiKey
+	}
+	if baseURL := c.String("base-url"); baseURL != "" {
+		entry.BaseURL = baseURL
+	}
+	if model := c.String("model"); model != "" {
+		entry.Model = model
+	}
+	if azureAPIVersion := c.String("azure-api-version"); azureAPIVersion != "" {
+		entry.AzureAPIVersion = azureAPIVersion
+	}
+
+	if err := cfg.SetFallback(entry); err != nil {
+		return fmt.Errorf("配置备用提供商失败: %w", err)
+	}
+	fmt.Printf("✓ 成功配置备用提供商 %s (%s)\n", entry.Name, entry.Provider)
+
+	var names []string
+	for _, e := range cfg.Entries() {
+		names = append(names, e.Name)
+	}
+	fmt.Printf("故障转移顺序: %s\n", strings.Join(names, " -> "))
 	fmt.Printf("配置文件: %s\n", cfg.ConfigFile())
 	return nil
 }
 
+func configUseAction(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		names, current := config.ListProfiles()
+		for _, n := range names {
+			if n == current {
+				fmt.Printf("* %s\n", n)
+			} else {
+				fmt.Printf("  %s\n", n)
+			}
+		}
+		return nil
+	}
+
+	if err := config.UseProfile(name); err != nil {
+		return fmt.Errorf("切换配置档案失败: %w", err)
+	}
+	fmt.Printf("✓ 默认配置档案已切换为: %s\n", name)
+	return nil
+}
+
+// reportOutput report 命令的 JSON 输出
+type reportOutput struct {
+	Author  string         `json:"author"`
+	Since   string         `json:"since"`
+	Until   string         `json:"until"`
+	Commits []reportCommit `json:"commits"`
+	Report  string         `json:"report"`
+}
+
+// reportCommit 日报涉及的一条提交
+type reportCommit struct {
+	Date    string `json:"date"`
+	Subject string `json:"subject"`
+}
+
+// reportCommits 把 "日期 -- 标题" 格式的提交记录转换为结构化的列表
+func reportCommits(commits []string) []reportCommit {
+	list := make([]reportCommit, 0, len(commits))
+	for _, commit := range commits {
+		date, subject, _ := strings.Cut(commit, " -- ")
+		list = append(list, reportCommit{Date: date, Subject: subject})
+	}
+	return list
+}
+
 func reportAction(c *cli.Context) error {
+	asJSON, err := jsonOutput(c)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		progress = os.Stderr
+	}
+
 	repo, err := git.GetRepo("")
 	if err != nil {
 		return fmt.Errorf("获取Git仓库失败: %w", err)
@@ -198,21 +665,28 @@ func reportAction(c *cli.Context) error {
 		return err
 	}
 
-	fmt.Printf("正在为 %s 获取 %s 到 %s 的提交记录...\n", authorEmail, since, until)
+	fmt.Fprintf(progress, "正在为 %s 获取 %s 到 %s 的提交记录...\n", authorEmail, since, until)
 	commits, err := repo.GetCommits(authorEmail, since, until)
 	if err != nil {
 		return fmt.Errorf("获取提交记录失败: %w", err)
 	}
 
+	output := reportOutput{Author: authorEmail, Since: since, Until: until, Commits: reportCommits(commits)}
 	if len(commits) == 0 {
+		if asJSON {
+			return printJSON(output)
+		}
 		fmt.Println("在指定时间范围内没有找到该作者的提交记录。")
 		return nil
 	}
 
-	fmt.Printf("找到 %d 条提交记录，正在生成日报...\n", len(commits))
+	fmt.Fprintf(progress, "找到 %d 条提交记录，正在生成日报...\n", len(commits))
 
 	// 加载配置
-	cfg := config.LoadConfig()
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
 
 	language := c.String("language")
 	if language == "" {
@@ -227,23 +701,9 @@ func reportAction(c *cli.Context) error {
 		}
 	}
 
-	apiKey := cfg.APIKey
-	if cfg.Provider == "azure" {
-		if cfg.APIKey == "" {
-			return fmt.Errorf("未配置 Azure OpenAI API 密钥，请先使用 'aicommit config --api-key YOUR_API_KEY' 配置")
-		}
-		if cfg.BaseURL == "" {
-			return fmt.Errorf("未配置 Azure OpenAI endpoint URL，请先使用 'aicommit config --base-url YOUR_ENDPOINT_URL' 配置")
-		}
-	} else {
-		if apiKey == "" {
-			return fmt.Errorf("未配置 OpenAI API 密钥，请先使用 'aicommit config --api-key YOUR_API_KEY' 配置")
-		}
-	}
-
-	aiProvider, err := ai.NewProvider(apiKey, cfg.BaseURL, cfg.Model, language, cfg.Provider, cfg.AzureAPIVersion)
+	aiProvider, err := newAIProvider(cfg, language)
 	if err != nil {
-		return fmt.Errorf("创建AI提供商实例失败: %w", err)
+		return err
 	}
 
 	reportInfo := &ai.ReportInfo{
@@ -255,6 +715,11 @@ func reportAction(c *cli.Context) error {
 		return fmt.Errorf("生成日报失败: %w", err)
 	}
 
+	if asJSON {
+		output.Report = reportContent
+		return printJSON(output)
+	}
+
 	fmt.Println("\n--- 生成的日报 ---")
 	fmt.Println(reportContent)
 	fmt.Println("--- 日报结束 ---")
@@ -262,6 +727,183 @@ func reportAction(c *cli.Context) error {
 	return nil
 }
 
+// newAIProvider 根据配置创建 AI 提供商实例，配置了备用提供商时返回故障转移链
+func newAIProvider(cfg *config.Config, language string) (ai.Provider, error) {
+	entries := cfg.Entries()
+	if len(entries) == 1 {
+		return newEntryProvider(cfg, entries[0], language)
+	}
+
+	var chain []ai.NamedProvider
+	var firstErr error
+	for _, entry := range entries {
+		provider, err := newEntryProvider(cfg, entry, language)
+		if err != nil {
+			// 配置不完整的提供商不参与故障转移
+			fmt.Fprintf(progress, "\033[33m⚠ 跳过提供商 %s: %v\033[0m\n", entry.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		chain = append(chain, ai.NamedProvider{Name: entry.Name, Provider: provider})
+	}
+	if len(chain) == 0 {
+		return nil, firstErr
+	}
+
+	fallback := ai.NewFallbackProvider(chain...)
+	fallback.OnFallback = printFallbackWarning
+	return fallback, nil
+}
+
+// newEntryProvider 创建单个提供商实例，缺少必要配置时给出提示
+func newEntryProvider(cfg *config.Config, entry config.ProviderEntry, language string) (ai.Provider, error) {
+	providerConfig := cfg.EntryConfig(entry, language)
+	if err := ai.ValidateConfig(entry.Provider, providerConfig); err != nil {
+		if _, isFallback := cfg.Fallback(entry.Name); isFallback {
+			return nil, fmt.Errorf("%w（备用提供商请加上 --fallback %s）", err, entry.Name)
+		}
+		return nil, err
+	}
+
+	aiProvider, err := ai.NewProvider(entry.Provider, providerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("创建AI提供商实例失败: %w", err)
+	}
+	return aiProvider, nil
+}
+
+// stagedDiff 获取暂存区的 diff，排除 .aicommitignore 和内置规则匹配的文件
+// 被排除的文件仍出现在返回的文件列表中，并带有 ai.ContentOmitted 标记
+func stagedDiff(repo *git.Repository, staged []string) (string, []string, error) {
+	return filteredDiff(repo, staged, func(exclude []string) (string, error) {
+		return repo.GetDiffExcluding(true, exclude)
+	})
+}
+
+// filteredDiff 调用 diff 获取 files 的差异内容，排除 .aicommitignore 和内置规则匹配的文件
+func filteredDiff(repo *git.Repository, files []string, diff func(exclude []string) (string, error)) (string, []string, error) {
+	root, err := repo.TopLevel()
+	if err != nil {
+		return "", nil, err
+	}
+	matcher, err := ignore.Load(root)
+	if err != nil {
+		return "", nil, err
+	}
+
+	_, omitted := matcher.Split(files)
+	content, err := diff(omitted)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(omitted) == 0 {
+		return content, files, nil
+	}
+
+	fmt.Fprintf(progress, "\033[90m以下文件只发送文件名 (%s): %s\033[0m\n", ignore.FileName, strings.Join(omitted, ", "))
+
+	marked := make([]string, 0, len(files))
+	for _, f := range files {
+		if matcher.Match(f) {
+			f += " " + ai.ContentOmitted
+		}
+		marked = append(marked, f)
+	}
+	return content, marked, nil
+}
+
+// stagedCommitInfo 准备生成提交消息所需的暂存区信息
+func stagedCommitInfo(cfg *config.Config, repo *git.Repository, staged []string) (*ai.CommitInfo, error) {
+	// 获取差异内容，.aicommitignore 排除的文件只发送文件名
+	diff, filesChanged, err := stagedDiff(repo, staged)
+	if err != nil {
+		return nil, err
+	}
+	return newCommitInfo(cfg, repo, diff, filesChanged)
+}
+
+// revisionCommitInfo 准备 base 到 rev 之间的变更信息，用于重新生成已有提交的提交消息
+// base 为空时表示根提交之前，rev 为空时表示暂存区（即 --amend 之后的提交内容）
+func revisionCommitInfo(cfg *config.Config, repo *git.Repository, base, rev string) (*ai.CommitInfo, []string, error) {
+	files, err := repo.GetChangesBetween(base, rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	diff, filesChanged, err := filteredDiff(repo, files, func(exclude []string) (string, error) {
+		return repo.GetDiffBetween(base, rev, exclude)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := newCommitInfo(cfg, repo, diff, filesChanged)
+	if err != nil {
+		return nil, nil, err
+	}
+	return info, files, nil
+}
+
+// newCommitInfo 检测 diff 中的密钥并补充分支信息
+func newCommitInfo(cfg *config.Config, repo *git.Repository, diff string, filesChanged []string) (*ai.CommitInfo, error) {
+	// 发送给 AI 前检测密钥
+	diff, err := scanDiff(cfg, diff)
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取当前分支
+	branch, err := repo.GetCurrentBranch()
+	if err != nil {
+		return nil, fmt.Errorf("获取当前分支失败: %w", err)
+	}
+
+	return &ai.CommitInfo{
+		FilesChanged: filesChanged,
+		DiffContent:  diff,
+		BranchName:   branch,
+	}, nil
+}
+
+// scanDiff 按配置检测 diff 中的密钥：替换为占位符，或列出位置后中止
+func scanDiff(cfg *config.Config, diff string) (string, error) {
+	scanner, mode, err := cfg.SecretScanner()
+	if err != nil {
+		return "", fmt.Errorf("密钥检测配置无效: %w", err)
+	}
+
+	switch mode {
+	case scan.ModeOff:
+		return diff, nil
+	case scan.ModeAbort:
+		findings := scanner.Scan(diff)
+		if len(findings) == 0 {
+			return diff, nil
+		}
+		fmt.Fprintf(progress, "\033[31m✗ 在暂存的更改中检测到 %d 处疑似密钥:\033[0m\n", len(findings))
+		printFindings(findings)
+		return "", fmt.Errorf("检测到疑似密钥，已中止（误报可在行内添加 %s 注释或使用 'aicommit config --secret-allow' 加入白名单）", scan.AllowMarker)
+	default:
+		redacted, findings := scanner.Redact(diff)
+		if len(findings) > 0 {
+			fmt.Fprintf(progress, "\033[33m⚠ 检测到 %d 处疑似密钥，已替换为占位符后再发送给 AI:\033[0m\n", len(findings))
+			printFindings(findings)
+		}
+		return redacted, nil
+	}
+}
+
+func printFindings(findings []scan.Finding) {
+	for _, f := range findings {
+		fmt.Fprintf(progress, "  %s\n", f)
+	}
+}
+
+// printFallbackWarning 提示主提供商失败并切换到备用提供商
+func printFallbackWarning(failed string, err error, next string) {
+	fmt.Fprintf(progress, "\033[33m⚠ %s 调用失败: %v\n  改用 %s 重试\033[0m\n", failed, err, next)
+}
+
 // parseDateRange 解析日期范围标志
 func parseDateRange(c *cli.Context) (since, until string, err error) {
 	dateFormat := "2006-01-02"
@@ -320,7 +962,7 @@ func parseDateRange(c *cli.Context) (since, until string, err error) {
 			endOfWeek := startOfWeek.AddDate(0, 0, 6)
 			since = startOfWeek.Format(dateFormat)
 			until = endOfWeek.Format(dateFormat)
-			fmt.Printf("未指定日期范围，默认使用本周 (%s - %s)\n", since, until)
+			fmt.Fprintf(progress, "未指定日期范围，默认使用本周 (%s - %s)\n", since, until)
 		}
 	}
 
@@ -337,63 +979,214 @@ func validateLanguage(lang string) error {
 	}
 }
 
-func checkAction(c *cli.Context) error {
-	cfg := config.LoadConfig()
+// checkOutput check 命令的 JSON 输出
+type checkOutput struct {
+	Profile       string              `json:"profile"`
+	RepoConfig    string              `json:"repo_config,omitempty"`
+	SecretBackend string              `json:"secret_backend,omitempty"`
+	Config        []config.FieldInfo  `json:"config"`
+	Providers     []providerCheckItem `json:"providers"`
+}
+
+// providerCheckItem 故障转移链中一个提供商的检测结果，无法创建提供商时只有 Error
+type providerCheckItem struct {
+	Name   string          `json:"name"`
+	Result *ai.CheckResult `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// checkProviders 依次检测主提供商和各备用提供商，不输出任何内容
+func checkProviders(c *cli.Context, cfg *config.Config) []providerCheckItem {
+	var items []providerCheckItem
+	for _, entry := range cfg.Entries() {
+		item := providerCheckItem{Name: entry.Name}
+		provider, err := newEntryProvider(cfg, entry, cfg.Language)
+		if err != nil {
+			item.Error = err.Error()
+		} else if checker, ok := provider.(ai.Checker); ok {
+			item.Result = checker.Check(c.Context)
+		} else {
+			item.Error = fmt.Sprintf("提供商 %s 不支持连通性检测", entry.Provider)
+		}
+		items = append(items, item)
+	}
+	return items
+}
 
-	// 创建 AI 提供商实例
-	provider, err := ai.NewProvider(
-		cfg.APIKey, cfg.BaseURL, cfg.Model,
-		cfg.Language, cfg.Provider, cfg.AzureAPIVersion,
-	)
+func checkAction(c *cli.Context) error {
+	asJSON, err := jsonOutput(c)
 	if err != nil {
-		fmt.Printf("\n✗ 创建 AI 提供商失败: %v\n", err)
-		return nil
+		return err
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return err
 	}
+	if asJSON {
+		return printJSON(checkOutput{
+			Profile:       cfg.Profile(),
+			RepoConfig:    cfg.RepoConfigPath(),
+			SecretBackend: string(cfg.SecretBackend()),
+			Config:        cfg.Fields(),
+			Providers:     checkProviders(c, cfg),
+		})
+	}
+
+	if cfg.Profile() != config.DefaultProfile {
+		fmt.Printf("配置档案: %s\n", cfg.Profile())
+	}
+	printConfigSources(cfg)
 
-	// 执行检测
-	result := provider.(*ai.OpenAIProvider).Check(c.Context)
-	ai.PrintCheckResult(result)
+	// 依次检测主提供商和各备用提供商
+	entries := cfg.Entries()
+	for i, entry := range entries {
+		if len(entries) > 1 {
+			fmt.Printf("\n[%d/%d] %s\n", i+1, len(entries), entry.Name)
+		}
+
+		provider, err := newEntryProvider(cfg, entry, cfg.Language)
+		if err != nil {
+			fmt.Printf("\n✗ %v\n", err)
+			continue
+		}
+
+		checker, ok := provider.(ai.Checker)
+		if !ok {
+			fmt.Printf("\n✗ 提供商 %s 不支持连通性检测\n", entry.Provider)
+			continue
+		}
+
+		// 执行检测
+		result := checker.Check(c.Context)
+		ai.PrintCheckResult(result)
+	}
 	return nil
 }
 
+// printConfigSources 打印各配置项的生效值及来源
+func printConfigSources(cfg *config.Config) {
+	fmt.Println("\n生效配置:")
+	if path := cfg.RepoConfigPath(); path != "" {
+		fmt.Printf("  仓库配置: %s\n", path)
+	}
+	if backend := cfg.SecretBackend(); backend != "" {
+		fmt.Printf("  密钥存储: %s\n", backend.Label())
+	}
+	for _, f := range cfg.Fields() {
+		value := f.Value
+		if value == "" {
+			value = "-"
+		}
+		source := f.Source.Label()
+		if f.Env != "" {
+			source += " " + f.Env
+		}
+		fmt.Printf("  %-18s %-30s \033[90m%s\033[0m\n", f.Name, value, source)
+	}
+}
+
 func defaultAction(c *cli.Context) error {
 	repo, err := git.GetRepo("")
 	if err != nil {
 		return fmt.Errorf("获取Git仓库失败: %w", err)
 	}
 
-	// 如果指定了提交消息，直接使用旧逻辑
-	if message := c.String("message"); message != "" {
-		staged, err := repo.GetStagedChanges()
+	// 加载配置
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+	if err := applyGenerationFlags(c, cfg); err != nil {
+		return err
+	}
+
+	n := c.Int("candidates")
+	if n < 1 || n > interactive.MaxCandidates {
+		return fmt.Errorf("候选数量必须在 1 到 %d 之间: %d", interactive.MaxCandidates, n)
+	}
+
+	// --amend 修改 HEAD，已推送的提交需要 --force
+	amend := c.Bool("amend")
+	if c.Bool("force") && !amend {
+		return fmt.Errorf("--force 只能与 --amend 一起使用")
+	}
+	var head string
+	if amend {
+		head, err = repo.ResolveCommit("HEAD")
 		if err != nil {
-			return fmt.Errorf("获取已暂存更改失败: %w", err)
+			return fmt.Errorf("还没有任何提交，无法使用 --amend")
 		}
-		if len(staged) == 0 {
-			return fmt.Errorf("没有找到已暂存的更改。使用 'git add' 来暂存你的更改")
+		if err := checkRewritable(repo, head, c.Bool("force")); err != nil {
+			return err
 		}
-		if err := repo.Commit(message); err != nil {
+	}
+	commit, committed := repo.Commit, "✓ 已提交更改"
+	if amend {
+		commit, committed = repo.AmendCommit, "✓ 已修改提交"
+	}
+
+	// 如果指定了提交消息，直接使用旧逻辑
+	if message := c.String("message"); message != "" {
+		if !amend {
+			staged, err := repo.GetStagedChanges()
+			if err != nil {
+				return fmt.Errorf("获取已暂存更改失败: %w", err)
+			}
+			if len(staged) == 0 {
+				return fmt.Errorf("没有找到已暂存的更改。使用 'git add' 来暂存你的更改")
+			}
+		}
+		if err := commit(message); err != nil {
 			return err
 		}
-		fmt.Printf("✓ 已提交更改：%s\n", message)
+		fmt.Printf("%s：%s\n", committed, message)
 		return nil
 	}
 
+	stage, yes, dryRun := c.String("stage"), c.Bool("yes"), c.Bool("dry-run")
+	asJSON, err := jsonOutput(c)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		// JSON 输出只生成消息，不提交
+		if yes {
+			return fmt.Errorf("--output json 不会提交，不能与 --yes 同时使用")
+		}
+		dryRun = true
+	}
+	if amend && stage == "" {
+		// 与 git commit --amend 一致，默认只加入已暂存的更改
+		stage = "none"
+	}
+	if err := checkNonInteractive(stage, yes, dryRun, n); err != nil {
+		return err
+	}
+	if dryRun {
+		// 标准输出只保留生成的提交消息，便于脚本读取
+		progress = os.Stderr
+		stage = "none"
+	}
+
 	// 获取所有变更
 	staged, modified, untracked, err := repo.GetAllChanges()
 	if err != nil {
 		return fmt.Errorf("获取变更失败: %w", err)
 	}
 
-	// 检查是否有任何变更
-	if len(staged) == 0 && len(modified) == 0 && len(untracked) == 0 {
-		fmt.Println("没有检测到任何变更")
+	// 检查是否有任何变更，--amend 没有新的变更时只重新生成提交消息
+	if !amend && len(staged) == 0 && len(modified) == 0 && len(untracked) == 0 {
+		fmt.Fprintln(progress, "没有检测到任何变更")
 		return nil
 	}
 
-	// 显示文件状态并让用户选择操作
-	action, err := interactive.ShowFileStatusAndSelect(staged, modified, untracked)
-	if err != nil {
-		return fmt.Errorf("交互式选择失败: %w", err)
+	// 显示文件状态并让用户选择操作，指定了 --stage 时不交互
+	action := stageActions[stage]
+	if stage == "" {
+		action, err = interactive.ShowFileStatusAndSelect(staged, modified, untracked)
+		if err != nil {
+			return fmt.Errorf("交互式选择失败: %w", err)
+		}
 	}
 
 	switch action {
@@ -417,6 +1210,11 @@ func defaultAction(c *cli.Context) error {
 			return err
 		}
 		fmt.Println("✓ 已暂存所有变更")
+	case "stage-tracked":
+		if err := repo.StageTracked(); err != nil {
+			return err
+		}
+		fmt.Println("✓ 已暂存已跟踪文件的修改")
 	case "cancel":
 		fmt.Println("操作已取消")
 		return nil
@@ -424,121 +1222,401 @@ func defaultAction(c *cli.Context) error {
 		return nil
 	}
 
-	// 重新获取已暂存的更改
-	staged, err = repo.GetStagedChanges()
-	if err != nil {
-		return fmt.Errorf("获取已暂存更改失败: %w", err)
+	var commitInfo *ai.CommitInfo
+	var files []string
+	if amend {
+		// 修改后的提交包含 HEAD 原有的更改和新暂存的更改
+		parent, err := repo.ParentOf(head)
+		if err != nil {
+			return err
+		}
+		commitInfo, files, err = revisionCommitInfo(cfg, repo, parent, "")
+		if err != nil {
+			return err
+		}
+		if current, err := repo.GetCommitMessage(head); err == nil {
+			title, _, _ := strings.Cut(current, "\n")
+			fmt.Fprintf(progress, "当前提交信息: %s\n", title)
+		}
+	} else {
+		// 重新获取已暂存的更改
+		files, err = repo.GetStagedChanges()
+		if err != nil {
+			return fmt.Errorf("获取已暂存更改失败: %w", err)
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("没有找到已暂存的更改")
+		}
+		commitInfo, err = stagedCommitInfo(cfg, repo, files)
+		if err != nil {
+			return err
+		}
 	}
 
-	if len(staged) == 0 {
-		return fmt.Errorf("没有找到已暂存的更改")
+	language, err := commitLanguage(c, cfg)
+	if err != nil {
+		return err
 	}
 
-	// 获取差异内容
-	diff, err := repo.GetDiff(true)
+	// 创建AI提供商实例
+	aiProvider, err := newAIProvider(cfg, language)
 	if err != nil {
-		return fmt.Errorf("获取差异内容失败: %w", err)
+		return err
 	}
 
-	// 获取当前分支
-	branch, err := repo.GetCurrentBranch()
+	// 生成提交消息的循环 (支持重新生成和根据反馈修改)，之前的候选会保留在列表中
+	batch, err := generateCandidates(context.Background(), aiProvider, commitInfo, n)
 	if err != nil {
-		return fmt.Errorf("获取当前分支失败: %w", err)
+		return fmt.Errorf("生成提交消息失败: %w", err)
 	}
+	rules := cfg.LintRules()
 
-	// 准备提交信息
-	commitInfo := &ai.CommitInfo{
-		FilesChanged: staged,
-		DiffContent:  diff,
-		BranchName:   branch,
+	// 非交互模式直接使用第一条消息
+	if dryRun || yes {
+		message := batch[0]
+		printGenerationStats(batch)
+		for _, w := range lintWarnings(message.Text(), rules) {
+			fmt.Fprintf(progress, "\033[33m⚠ %s\033[0m\n", w)
+		}
+		if asJSON {
+			return printJSON(newCommitOutput(cfg, message, files))
+		}
+		if dryRun {
+			fmt.Println(message.Text())
+			return nil
+		}
+		if err := commit(message.Text()); err != nil {
+			return err
+		}
+		fmt.Printf("%s：%s\n", committed, message.Title)
+		return nil
 	}
 
-	// 加载配置
-	cfg := config.LoadConfig()
+	commitMessage, err := chooseCommitMessage(aiProvider, commitInfo, n, rules, batch)
+	if err != nil || commitMessage == "" {
+		return err
+	}
+	if err := commit(commitMessage); err != nil {
+		return err
+	}
+	fmt.Println(committed)
+	return nil
+}
 
-	// 获取语言设置，优先使用命令行参数
-	language := c.String("language")
-	if language == "" {
-		language = cfg.Language
-	} else {
-		if err := validateLanguage(language); err != nil {
+// applyGenerationFlags 用 --diff-mode 和 --secret-scan 覆盖本次运行的配置
+func applyGenerationFlags(c *cli.Context, cfg *config.Config) error {
+	if mode := c.String("diff-mode"); mode != "" {
+		if err := cfg.Override("diff_mode", mode, config.SourceFlag); err != nil {
 			return err
 		}
-		if language == "zh" {
-			language = "zh-CN"
+	}
+	if mode := c.String("secret-scan"); mode != "" {
+		if err := cfg.Override("secret_scan", mode, config.SourceFlag); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	// 创建AI提供商实例
-	apiKey := cfg.APIKey
-	if cfg.Provider == "azure" {
-		if cfg.APIKey == "" {
-			return fmt.Errorf("未配置 Azure OpenAI API 密钥，请先使用 'aicommit config --api-key YOUR_API_KEY' 配置")
-		}
-		if cfg.BaseURL == "" {
-			return fmt.Errorf("未配置 Azure OpenAI endpoint URL，请先使用 'aicommit config --base-url YOUR_ENDPOINT_URL' 配置")
-		}
-	} else {
-		if apiKey == "" {
-			return fmt.Errorf("未配置 OpenAI API 密钥，请先使用 'aicommit config --api-key YOUR_API_KEY' 配置")
-		}
+// commitLanguage 返回提交消息的语言，优先使用命令行参数
+func commitLanguage(c *cli.Context, cfg *config.Config) (string, error) {
+	language := c.String("language")
+	if language == "" {
+		return cfg.Language, nil
 	}
-	aiProvider, err := ai.NewProvider(apiKey, cfg.BaseURL, cfg.Model, language, cfg.Provider, cfg.AzureAPIVersion)
-	if err != nil {
-		return fmt.Errorf("创建AI提供商实例失败: %w", err)
+	if err := validateLanguage(language); err != nil {
+		return "", err
+	}
+	if language == "zh" {
+		language = "zh-CN"
 	}
+	return language, nil
+}
 
-	// 生成提交消息的循环 (支持重新生成)
+// chooseCommitMessage 显示生成的候选并让用户选择、编辑、重新生成或根据反馈修改
+// 返回用户确认的提交消息，取消时返回空字符串；之前的候选会保留在列表中
+func chooseCommitMessage(aiProvider ai.Provider, commitInfo *ai.CommitInfo, n int, rules lint.Rules, batch []*ai.CommitMessage) (string, error) {
+	var candidates []*ai.CommitMessage
+	selected := 0
 	for {
-		fmt.Println("\n正在生成提交消息...")
-		message, err := aiProvider.GenerateCommitMessage(context.Background(), commitInfo)
-		if err != nil {
-			return fmt.Errorf("生成提交消息失败: %w", err)
+		// 合并新生成的候选并选中其中第一条
+		if len(batch) > 0 {
+			printGenerationStats(batch)
+			candidates, selected = appendCandidates(candidates, batch)
+			if len(candidates) > interactive.MaxCandidates {
+				drop := len(candidates) - interactive.MaxCandidates
+				candidates = candidates[drop:]
+				selected = max(selected-drop, 0)
+			}
+			batch = nil
 		}
 
 		// 显示生成的消息并让用户选择操作
-		action, err := interactive.ShowCommitMessage(message.Title, message.Body)
+		var action interactive.CommitAction
+		var err error
+		if len(candidates) == 1 {
+			action, err = interactive.ShowCommitMessage(candidates[0].Title, candidates[0].Body, lintWarnings(candidates[0].Text(), rules))
+		} else {
+			items := make([]interactive.Candidate, len(candidates))
+			for i, m := range candidates {
+				items[i] = interactive.Candidate{Title: m.Title, Body: m.Body, Warnings: lintWarnings(m.Text(), rules)}
+			}
+			action, selected, err = interactive.ShowCandidates(items, selected)
+		}
 		if err != nil {
-			return fmt.Errorf("交互式选择失败: %w", err)
+			return "", fmt.Errorf("交互式选择失败: %w", err)
 		}
 
-		commitMessage := message.Title
-		if message.Body != "" {
-			commitMessage += "\n\n" + message.Body
+		message := candidates[selected]
+		commitMessage := message.Text()
+
+		// 编辑后的消息符合规范时直接提交，否则显示问题并让用户重新选择
+		if action == interactive.ActionEdit {
+			edited, next, err := editCommitMessage(commitMessage, rules)
+			if err != nil {
+				return "", err
+			}
+			commitMessage, action = edited, next
 		}
 
 		switch action {
 		case interactive.ActionAccept:
-			if err := repo.Commit(commitMessage); err != nil {
-				return err
+			return commitMessage, nil
+
+		case interactive.ActionRegenerate:
+			batch, err = generateCandidates(context.Background(), aiProvider, commitInfo, n)
+			if err != nil {
+				return "", fmt.Errorf("生成提交消息失败: %w", err)
 			}
-			fmt.Println("✓ 已提交更改")
-			return nil
 
-		case interactive.ActionEdit:
-			edited, err := interactive.EditMessage(commitMessage)
+		case interactive.ActionRefine:
+			feedback, err := interactive.PromptFeedback()
 			if err != nil {
-				return fmt.Errorf("编辑消息失败: %w", err)
+				return "", fmt.Errorf("读取修改意见失败: %w", err)
 			}
-			if strings.TrimSpace(edited) == "" {
-				fmt.Println("提交消息为空，操作取消")
-				return nil
+			if feedback == "" {
+				fmt.Println("未输入修改意见，保留当前消息")
+				continue
 			}
-			if err := repo.Commit(edited); err != nil {
-				return err
+			refined, err := streamCommitMessage(aiProvider, "正在根据反馈修改提交消息...", func(onToken func(string)) (*ai.CommitMessage, error) {
+				return aiProvider.RefineCommitMessage(context.Background(), message, feedback, onToken)
+			})
+			if err != nil {
+				return "", fmt.Errorf("修改提交消息失败: %w", err)
 			}
-			fmt.Println("✓ 已提交更改")
-			return nil
-
-		case interactive.ActionRegenerate:
-			// 继续循环，重新生成
-			continue
+			batch = []*ai.CommitMessage{refined}
 
 		case interactive.ActionCancel:
 			fmt.Println("提交已取消")
-			return nil
+			return "", nil
+		}
+	}
+}
+
+// commitOutput --output json 时输出的提交消息
+type commitOutput struct {
+	Type     string   `json:"type"`
+	Scope    string   `json:"scope"`
+	Title    string   `json:"title"`
+	Body     string   `json:"body"`
+	Files    []string `json:"files"`
+	Provider string   `json:"provider"`
+	Model    string   `json:"model"`
+	Usage    ai.Usage `json:"usage"`
+}
+
+// newCommitOutput 转换为 JSON 输出，没有经过故障转移时提供商为主提供商
+func newCommitOutput(cfg *config.Config, message *ai.CommitMessage, files []string) commitOutput {
+	provider := message.Provider
+	if provider == "" {
+		provider = cfg.Provider
+	}
+	return commitOutput{
+		Type:     message.Type,
+		Scope:    message.Scope,
+		Title:    message.Title,
+		Body:     message.Body,
+		Files:    files,
+		Provider: provider,
+		Model:    message.Model,
+		Usage:    message.Usage,
+	}
+}
+
+// stageActions --stage 的取值对应的暂存操作，与交互选择的结果一致
+var stageActions = map[string]string{
+	"none":    "use-staged",
+	"all":     "stage-all",
+	"tracked": "stage-tracked",
+}
+
+// checkNonInteractive 校验非交互参数的组合
+// 标准输入不是终端时无法读取按键，需要交互的步骤必须由参数指定，避免一直等待输入
+func checkNonInteractive(stage string, yes, dryRun bool, candidates int) error {
+	if _, ok := stageActions[stage]; stage != "" && !ok {
+		return fmt.Errorf("不支持的暂存方式: %s，请使用 none, all 或 tracked", stage)
+	}
+	if yes && dryRun {
+		return fmt.Errorf("--yes 和 --dry-run 不能同时使用")
+	}
+	if dryRun && (stage == "all" || stage == "tracked") {
+		return fmt.Errorf("--dry-run 和 --output json 不会修改暂存区，只能与 --stage=none 一起使用")
+	}
+	if (yes || dryRun) && candidates > 1 {
+		return fmt.Errorf("--candidates 需要交互选择，不能与 --yes、--dry-run 或 --output json 同时使用")
+	}
+
+	if interactive.IsInputTerminal() {
+		return nil
+	}
+	if stage == "" && !dryRun {
+		return fmt.Errorf("标准输入不是终端，无法交互选择文件，请使用 --stage=none|all|tracked 指定暂存方式")
+	}
+	if !yes && !dryRun {
+		return fmt.Errorf("标准输入不是终端，无法确认提交消息，请使用 --yes 直接提交或 --dry-run 只输出消息")
+	}
+	return nil
+}
+
+// editCommitMessage 打开编辑器修改提交消息，符合规范时返回 ActionAccept
+// 不符合规范时显示问题，用户可以仍然提交、继续编辑或选择其他操作
+func editCommitMessage(content string, rules lint.Rules) (string, interactive.CommitAction, error) {
+	for {
+		edited, err := interactive.EditMessage(content)
+		if err != nil {
+			return "", interactive.ActionCancel, fmt.Errorf("编辑消息失败: %w", err)
+		}
+		if strings.TrimSpace(edited) == "" {
+			fmt.Println("提交消息为空")
+			return "", interactive.ActionCancel, nil
+		}
+
+		warnings := lintWarnings(edited, rules)
+		if len(warnings) == 0 {
+			return edited, interactive.ActionAccept, nil
+		}
+
+		title, body, _ := strings.Cut(edited, "\n\n")
+		action, err := interactive.ShowCommitMessage(title, strings.TrimSpace(body), warnings)
+		if err != nil {
+			return "", interactive.ActionCancel, fmt.Errorf("交互式选择失败: %w", err)
+		}
+		if action != interactive.ActionEdit {
+			return edited, action, nil
+		}
+		content = edited
+	}
+}
+
+// lintWarnings 检查提交消息是否符合规范，返回用于显示的问题列表
+func lintWarnings(message string, rules lint.Rules) []string {
+	var warnings []string
+	for _, issue := range lint.Check(message, rules) {
+		warnings = append(warnings, issue.String())
+	}
+	return warnings
+}
+
+// generateCandidates 生成 n 条候选提交消息，只生成一条时实时显示模型输出
+func generateCandidates(ctx context.Context, aiProvider ai.Provider, commitInfo *ai.CommitInfo, n int) ([]*ai.CommitMessage, error) {
+	if n == 1 {
+		message, err := generateCommitMessage(ctx, aiProvider, commitInfo)
+		if err != nil {
+			return nil, err
+		}
+		return []*ai.CommitMessage{message}, nil
+	}
+
+	fmt.Fprintf(progress, "\n正在生成 %d 条候选提交消息...\n", n)
+	return ai.GenerateCandidates(ctx, aiProvider, commitInfo, n)
+}
+
+// appendCandidates 把新生成的候选追加到列表末尾，跳过与已有候选相同的消息
+// 返回合并后的列表和 batch 中第一条候选在列表中的下标
+func appendCandidates(candidates, batch []*ai.CommitMessage) ([]*ai.CommitMessage, int) {
+	first := -1
+	for _, m := range batch {
+		i := slices.IndexFunc(candidates, func(existing *ai.CommitMessage) bool {
+			return existing.Title == m.Title && existing.Body == m.Body
+		})
+		if i < 0 {
+			candidates = append(candidates, m)
+			i = len(candidates) - 1
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	return candidates, max(first, 0)
+}
+
+// printGenerationStats 打印生成过程中的提示、生成候选所用的提供商和合计 token 用量
+func printGenerationStats(batch []*ai.CommitMessage) {
+	var providers, warnings []string
+	var usage ai.Usage
+	for _, m := range batch {
+		if m.Provider != "" && !slices.Contains(providers, m.Provider) {
+			providers = append(providers, m.Provider)
+		}
+		for _, w := range m.Warnings {
+			if !slices.Contains(warnings, w) {
+				warnings = append(warnings, w)
+			}
 		}
+		usage.PromptTokens += m.Usage.PromptTokens
+		usage.CompletionTokens += m.Usage.CompletionTokens
+		usage.TotalTokens += m.Usage.TotalTokens
 	}
+
+	for _, w := range warnings {
+		fmt.Fprintf(progress, "\033[33m⚠ %s\033[0m\n", w)
+	}
+	if len(providers) > 0 {
+		fmt.Fprintf(progress, "\033[90m由 %s 生成\033[0m\n", strings.Join(providers, ", "))
+	}
+	if usage.TotalTokens > 0 {
+		fmt.Fprintf(progress, "\033[90mToken 用量: 输入 %d / 输出 %d / 合计 %d\033[0m\n",
+			usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+	}
+}
+
+// generateCommitMessage 生成提交消息，终端中实时显示模型输出，否则等待完整结果
+func generateCommitMessage(ctx context.Context, aiProvider ai.Provider, commitInfo *ai.CommitInfo) (*ai.CommitMessage, error) {
+	return streamCommitMessage(aiProvider, "正在生成提交消息...", func(onToken func(string)) (*ai.CommitMessage, error) {
+		if onToken == nil {
+			return aiProvider.GenerateCommitMessage(ctx, commitInfo)
+		}
+		return aiProvider.GenerateCommitMessageStream(ctx, commitInfo, onToken)
+	})
+}
+
+// streamCommitMessage 调用 generate 生成提交消息，终端中传入 onToken 实时显示模型输出，否则传入 nil
+// 进度信息不输出到标准输出时同样不实时显示
+func streamCommitMessage(aiProvider ai.Provider, title string, generate func(onToken func(string)) (*ai.CommitMessage, error)) (*ai.CommitMessage, error) {
+	if progress != io.Writer(os.Stdout) || !interactive.IsTerminal() {
+		fmt.Fprintln(progress, "\n"+title)
+		return generate(nil)
+	}
+
+	box := interactive.NewStreamBox(title)
+	if fallback, ok := aiProvider.(*ai.FallbackProvider); ok {
+		// 只在本次流式生成期间接管提示，结束后恢复原来的处理函数
+		previous := fallback.OnFallback
+		defer func() { fallback.OnFallback = previous }()
+		fallback.OnFallback = func(failed string, err error, next string) {
+			// 擦除失败提供商已输出的内容，提示后重新开始
+			box.Close()
+			if previous != nil {
+				previous(failed, err, next)
+			}
+			box = interactive.NewStreamBox(title)
+		}
+	}
+
+	// 故障转移时会换成新的 box，不能直接传入 box.Write
+	message, err := generate(func(token string) { box.Write(token) })
+	box.Close()
+	return message, err
 }
 
 // 添加版本信息处理函数
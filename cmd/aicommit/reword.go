@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SimonGino/aicommit/internal/git"
+	"github.com/SimonGino/aicommit/internal/interactive"
+	"github.com/urfave/cli/v2"
+)
+
+// checkRewritable 检查提交是否已经推送，已推送的提交改写后需要强制推送，必须由 --force 确认
+func checkRewritable(repo *git.Repository, hash string, force bool) error {
+	if force {
+		return nil
+	}
+	branches, err := repo.RemoteBranchesContaining(hash)
+	if err != nil {
+		return err
+	}
+	if len(branches) > 0 {
+		return fmt.Errorf("提交 %s 已推送到 %s，改写后需要强制推送；确认要改写请加上 --force", shortHash(hash), strings.Join(branches, ", "))
+	}
+	return nil
+}
+
+// rewriteBase 检查从 hash 开始到 HEAD 的历史能否通过 rebase 改写，返回 rebase 的起点（hash 的父提交）
+// hash 必须在当前分支上，之后不能有合并提交，已推送时需要 force
+func rewriteBase(repo *git.Repository, hash string, force bool) (string, error) {
+	if onBranch, err := repo.IsAncestor(hash, "HEAD"); err != nil {
+		return "", err
+	} else if !onBranch {
+		return "", fmt.Errorf("提交 %s 不在当前分支上", shortHash(hash))
+	}
+	parent, err := repo.ParentOf(hash)
+	if err != nil {
+		return "", err
+	}
+	if merges, err := repo.HasMerges(parent); err != nil {
+		return "", err
+	} else if merges {
+		return "", fmt.Errorf("提交 %s 及之后的历史中有合并提交，无法通过 rebase 修改", shortHash(hash))
+	}
+	if err := checkRewritable(repo, hash, force); err != nil {
+		return "", err
+	}
+	return parent, nil
+}
+
+// rewordAction 根据提交自身的 diff 重新生成提交信息，并通过 rebase 改写该提交
+func rewordAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("请指定一个要修改的提交，如 aicommit reword HEAD~2")
+	}
+	repo, err := git.GetRepo("")
+	if err != nil {
+		return fmt.Errorf("获取Git仓库失败: %w", err)
+	}
+
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+	if err := applyGenerationFlags(c, cfg); err != nil {
+		return err
+	}
+
+	n := c.Int("candidates")
+	if n < 1 || n > interactive.MaxCandidates {
+		return fmt.Errorf("候选数量必须在 1 到 %d 之间: %d", interactive.MaxCandidates, n)
+	}
+	yes := c.Bool("yes")
+	if yes && n > 1 {
+		return fmt.Errorf("--candidates 需要交互选择，不能与 --yes 同时使用")
+	}
+	if !yes && !interactive.IsInputTerminal() {
+		return fmt.Errorf("标准输入不是终端，无法确认提交消息，请使用 --yes 直接修改")
+	}
+
+	hash, err := repo.ResolveCommit(c.Args().First())
+	if err != nil {
+		return err
+	}
+	parent, err := rewriteBase(repo, hash, c.Bool("force"))
+	if err != nil {
+		return err
+	}
+
+	if current, err := repo.GetCommitMessage(hash); err == nil {
+		title, _, _ := strings.Cut(current, "\n")
+		fmt.Printf("当前提交信息: %s %s\n", shortHash(hash), title)
+	}
+
+	commitInfo, _, err := revisionCommitInfo(cfg, repo, parent, hash)
+	if err != nil {
+		return err
+	}
+	language, err := commitLanguage(c, cfg)
+	if err != nil {
+		return err
+	}
+	aiProvider, err := newAIProvider(cfg, language)
+	if err != nil {
+		return err
+	}
+
+	batch, err := generateCandidates(context.Background(), aiProvider, commitInfo, n)
+	if err != nil {
+		return fmt.Errorf("生成提交消息失败: %w", err)
+	}
+	rules := cfg.LintRules()
+
+	var commitMessage string
+	if yes {
+		printGenerationStats(batch)
+		for _, w := range lintWarnings(batch[0].Text(), rules) {
+			fmt.Printf("\033[33m⚠ %s\033[0m\n", w)
+		}
+		commitMessage = batch[0].Text()
+	} else {
+		commitMessage, err = chooseCommitMessage(aiProvider, commitInfo, n, rules, batch)
+		if err != nil || commitMessage == "" {
+			return err
+		}
+	}
+
+	if err := repo.RewordCommits(parent, map[string]string{hash: commitMessage}); err != nil {
+		return err
+	}
+	title, _, _ := strings.Cut(commitMessage, "\n")
+	fmt.Printf("✓ 已修改提交 %s 的提交信息：%s\n", shortHash(hash), title)
+	fmt.Println("  之后的提交哈希都已改变，可以使用 'git reset --keep ORIG_HEAD' 撤销")
+	return nil
+}
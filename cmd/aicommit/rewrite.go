@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SimonGino/aicommit/internal/ai"
+	"github.com/SimonGino/aicommit/internal/config"
+	"github.com/SimonGino/aicommit/internal/git"
+	"github.com/SimonGino/aicommit/internal/interactive"
+	"github.com/SimonGino/aicommit/internal/lint"
+	"github.com/urfave/cli/v2"
+)
+
+// backupRefPrefix 改写前的分支位置保存在 refs/aicommit/backup/<分支名> 下，用于撤销
+const backupRefPrefix = "refs/aicommit/backup/"
+
+// backupRef 返回当前分支的备份引用，HEAD 分离时使用 HEAD
+func backupRef(repo *git.Repository) (string, error) {
+	branch, err := repo.GetCurrentBranch()
+	if err != nil {
+		return "", fmt.Errorf("获取当前分支失败: %w", err)
+	}
+	return backupRefPrefix + branch, nil
+}
+
+// rewriteRangeAction 为范围内的每个提交根据其自身的 diff 生成提交信息，确认后通过一次 rebase 全部改写
+func rewriteRangeAction(c *cli.Context) error {
+	repo, err := git.GetRepo("")
+	if err != nil {
+		return fmt.Errorf("获取Git仓库失败: %w", err)
+	}
+	if c.Bool("undo") {
+		if c.NArg() > 0 {
+			return fmt.Errorf("--undo 不需要指定提交范围")
+		}
+		return undoRewrite(repo, c.Bool("force"))
+	}
+	if c.NArg() != 1 {
+		return fmt.Errorf("请指定一个要改写的提交范围，如 aicommit rewrite-range origin/main..HEAD")
+	}
+	revRange := c.Args().First()
+	if !strings.Contains(revRange, "..") {
+		// 单个提交会被 git log 当作它的全部历史
+		return fmt.Errorf("请使用 <起点>..<终点> 形式的范围，如 %s..HEAD", revRange)
+	}
+
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+	if err := applyGenerationFlags(c, cfg); err != nil {
+		return err
+	}
+	yes, dryRun := c.Bool("yes"), c.Bool("dry-run")
+	if yes && dryRun {
+		return fmt.Errorf("--yes 和 --dry-run 不能同时使用")
+	}
+	if !yes && !dryRun && !interactive.IsInputTerminal() {
+		return fmt.Errorf("标准输入不是终端，无法确认修改，请使用 --yes 直接改写或 --dry-run 只查看")
+	}
+
+	entries, err := repo.GetCommitMessages(revRange)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("范围内没有提交")
+		return nil
+	}
+	// 范围内的提交都必须在当前分支上，rebase 从最早的提交开始
+	for _, entry := range entries[1:] {
+		if onBranch, err := repo.IsAncestor(entry.Hash, "HEAD"); err != nil {
+			return err
+		} else if !onBranch {
+			return fmt.Errorf("提交 %s 不在当前分支上", shortHash(entry.Hash))
+		}
+	}
+	base, err := rewriteBase(repo, entries[0].Hash, c.Bool("force"))
+	if err != nil {
+		return err
+	}
+
+	language, err := commitLanguage(c, cfg)
+	if err != nil {
+		return err
+	}
+	aiProvider, err := newAIProvider(cfg, language)
+	if err != nil {
+		return err
+	}
+	rules := cfg.LintRules()
+
+	rows, messages, err := generateRewrites(cfg, repo, aiProvider, entries)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	interactive.ShowRewriteTable(rows)
+	for _, entry := range entries {
+		message, ok := messages[entry.Hash]
+		if !ok {
+			continue
+		}
+		for _, w := range lintWarnings(message, rules) {
+			fmt.Printf("\033[33m⚠ %s: %s\033[0m\n", shortHash(entry.Hash), w)
+		}
+	}
+	fmt.Println()
+
+	if len(messages) == 0 {
+		fmt.Println("没有需要修改的提交信息")
+		return nil
+	}
+	if dryRun {
+		return nil
+	}
+	if !yes {
+		confirmed, err := interactive.PromptConfirm(fmt.Sprintf("改写以上 %d 个提交的提交信息", len(messages)))
+		if err != nil {
+			return fmt.Errorf("读取确认失败: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("操作已取消")
+			return nil
+		}
+	}
+
+	// 先保存改写前的位置，成功后可以用 --undo 撤销；rebase 失败时会自动中止，不需要备份
+	ref, err := backupRef(repo)
+	if err != nil {
+		return err
+	}
+	head, err := repo.ResolveCommit("HEAD")
+	if err != nil {
+		return err
+	}
+	if err := repo.UpdateRef(ref, head); err != nil {
+		return err
+	}
+	if err := repo.RewordCommits(base, messages); err != nil {
+		_ = repo.DeleteRef(ref)
+		return err
+	}
+
+	fmt.Printf("✓ 已改写 %d 个提交的提交信息\n", len(messages))
+	fmt.Printf("  改写前的历史保存在 %s，可以使用 'aicommit rewrite-range --undo' 撤销\n", ref)
+	return nil
+}
+
+// generateRewrites 依次为每个提交生成新的提交信息，返回对照表的行和需要修改的提交信息（以完整哈希为键）
+// 合并、回滚和 fixup! 等 git 生成的提交信息保持不变，生成结果与原来相同的提交不做修改
+func generateRewrites(cfg *config.Config, repo *git.Repository, aiProvider ai.Provider, entries []git.LogEntry) ([]interactive.RewriteRow, map[string]string, error) {
+	rows := make([]interactive.RewriteRow, 0, len(entries))
+	messages := make(map[string]string)
+	var generated []*ai.CommitMessage
+	for i, entry := range entries {
+		title, _, _ := strings.Cut(entry.Message, "\n")
+		row := interactive.RewriteRow{Commit: shortHash(entry.Hash), Old: title}
+		if lint.Ignored(entry.Message) {
+			row.Unchanged = true
+			rows = append(rows, row)
+			continue
+		}
+
+		fmt.Fprintf(progress, "[%d/%d] 正在为 %s 生成提交信息...\n", i+1, len(entries), shortHash(entry.Hash))
+		parent, err := repo.ParentOf(entry.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		commitInfo, _, err := revisionCommitInfo(cfg, repo, parent, entry.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		message, err := aiProvider.GenerateCommitMessage(context.Background(), commitInfo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("为提交 %s 生成提交信息失败: %w", shortHash(entry.Hash), err)
+		}
+		generated = append(generated, message)
+
+		row.New = message.Title
+		if message.Text() == entry.Message {
+			row.Unchanged = true
+		} else {
+			messages[entry.Hash] = message.Text()
+		}
+		rows = append(rows, row)
+	}
+	printGenerationStats(generated)
+	return rows, messages, nil
+}
+
+// undoRewrite 把当前分支恢复到最近一次 rewrite-range 之前的位置
+// 改写只修改提交信息，当前的文件内容与备份不同说明之后又有新的提交，需要 force 才会丢弃
+func undoRewrite(repo *git.Repository, force bool) error {
+	ref, err := backupRef(repo)
+	if err != nil {
+		return err
+	}
+	backup, err := repo.ResolveCommit(ref)
+	if err != nil {
+		return fmt.Errorf("当前分支没有可以撤销的改写")
+	}
+
+	if !force {
+		backupTree, err := repo.TreeOf(backup)
+		if err != nil {
+			return err
+		}
+		headTree, err := repo.TreeOf("HEAD")
+		if err != nil {
+			return err
+		}
+		if backupTree != headTree {
+			return fmt.Errorf("改写之后又有新的提交，撤销会丢弃这些提交；确认要撤销请加上 --force")
+		}
+	}
+
+	if err := repo.ResetKeep(backup); err != nil {
+		return err
+	}
+	if err := repo.DeleteRef(ref); err != nil {
+		return err
+	}
+	fmt.Printf("✓ 已恢复到改写前的历史 (%s)\n", shortHash(backup))
+	return nil
+}
@@ -4,15 +4,24 @@ go 1.24.0
 
 toolchain go1.24.11
 
-require github.com/urfave/cli/v2 v2.27.5
+require (
+	github.com/manifoldco/promptui v0.9.0
+	github.com/mattn/go-runewidth v0.0.19
+	github.com/tiktoken-go/tokenizer v0.7.0
+	github.com/urfave/cli/v2 v2.27.5
+	github.com/zalando/go-keyring v0.2.6
+	golang.org/x/crypto v0.46.0
+	golang.org/x/term v0.38.0
+)
 
 require (
+	al.essio.dev/pkg/shellescape v1.5.1 // indirect
 	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
 	github.com/clipperhouse/uax29/v2 v2.2.0 // indirect
-	github.com/manifoldco/promptui v0.9.0 // indirect
-	github.com/mattn/go-runewidth v0.0.19 // indirect
+	github.com/danieljoos/wincred v1.2.2 // indirect
+	github.com/dlclark/regexp2 v1.11.5 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
-	golang.org/x/term v0.38.0 // indirect
 )
 
 require (
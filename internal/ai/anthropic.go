@@ -0,0 +1,335 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// AnthropicDefaultBaseURL Anthropic API 的默认地址
+	AnthropicDefaultBaseURL = "https://api.anthropic.com"
+	// AnthropicDefaultModel Anthropic 的默认模型
+	AnthropicDefaultModel = "claude-sonnet-4-5"
+	// anthropicVersion Messages API 要求的版本头
+	anthropicVersion = "2023-06-01"
+)
+
+// AnthropicProvider 使用 Anthropic Messages API 实现 Provider
+type AnthropicProvider struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	language   string
+	types      []string // 允许的提交类型，为空时使用默认类型
+	httpClient *http.Client
+	retry      RetryPolicy
+	diff       DiffPolicy
+}
+
+// AnthropicAPIError 表示 Anthropic API 返回的错误
+type AnthropicAPIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *AnthropicAPIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// anthropicMessage Messages API 中的单条消息
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// anthropicRequest Messages API 请求体，system 为顶层字段
+type anthropicRequest struct {
+	Model       string               `json:"model"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature float32              `json:"temperature,omitempty"`
+	Stream      bool                 `json:"stream,omitempty"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// anthropicTool 工具定义，结构化输出通过强制调用工具实现
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// anthropicToolChoice 指定模型必须调用的工具
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// anthropicResponse Messages API 响应体
+type anthropicResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// anthropicStreamEvent 流式响应中的单个事件，仅解析需要的字段
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicErrorResponse Messages API 错误响应体
+type anthropicErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func init() {
+	Register("anthropic", Factory{
+		DisplayName:  "Anthropic",
+		Required:     []ConfigField{FieldAPIKey},
+		DefaultModel: AnthropicDefaultModel,
+		APIKeyEnv:    "ANTHROPIC_API_KEY",
+		New: func(cfg ProviderConfig) (Provider, error) {
+			return newAnthropicProvider(cfg)
+		},
+	})
+}
+
+// newAnthropicProvider 创建 Anthropic Provider 实例
+func newAnthropicProvider(cfg ProviderConfig) (*AnthropicProvider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("Anthropic API 密钥不能为空")
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = AnthropicDefaultBaseURL
+	}
+	model := cfg.Model
+	if model == "" {
+		model = AnthropicDefaultModel
+	}
+
+	return &AnthropicProvider{
+		apiKey:     cfg.APIKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		language:   cfg.Language,
+		types:      cfg.CommitTypes,
+		httpClient: newHTTPClient(proxyTransport()),
+		retry:      retryPolicyFrom(cfg),
+		diff:       diffPolicyFrom(cfg),
+	}, nil
+}
+
+// GenerateCommitMessage 使用 Anthropic API 生成提交消息
+func (p *AnthropicProvider) GenerateCommitMessage(ctx context.Context, info *CommitInfo) (*CommitMessage, error) {
+	return generateCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, info, p.diff, nil)
+}
+
+// GenerateCommitMessageStream 使用 Anthropic API 流式生成提交消息
+func (p *AnthropicProvider) GenerateCommitMessageStream(ctx context.Context, info *CommitInfo, onToken func(token string)) (*CommitMessage, error) {
+	return generateCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, info, p.diff, onToken)
+}
+
+// RefineCommitMessage 根据用户反馈修改提交消息
+func (p *AnthropicProvider) RefineCommitMessage(ctx context.Context, previous *CommitMessage, feedback string, onToken func(token string)) (*CommitMessage, error) {
+	return refineCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, previous, feedback, onToken)
+}
+
+// GenerateDailyReport 使用 Anthropic API 生成日报
+func (p *AnthropicProvider) GenerateDailyReport(ctx context.Context, info *ReportInfo, since, until string) (string, error) {
+	return generateDailyReport(ctx, withRetry(p, p.retry), p.language, info, since, until)
+}
+
+// Check 测试 Anthropic API 连通性
+func (p *AnthropicProvider) Check(ctx context.Context) *CheckResult {
+	return runCheck(ctx, p, &CheckResult{
+		Provider: "anthropic",
+		Model:    p.model,
+		BaseURL:  p.baseURL,
+	}, p.apiKey)
+}
+
+// messagesURL 返回 Messages API 地址，兼容以 /v1 结尾的自定义地址
+func (p *AnthropicProvider) messagesURL() string {
+	if strings.HasSuffix(p.baseURL, "/v1") {
+		return p.baseURL + "/messages"
+	}
+	return p.baseURL + "/v1/messages"
+}
+
+// chat 将通用请求转换为 Anthropic Messages API 请求
+func (p *AnthropicProvider) chat(ctx context.Context, req *chatRequest) (*chatResponse, error) {
+	body := anthropicRequest{
+		Model:       p.model,
+		System:      req.System,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Stream:      req.OnToken != nil,
+	}
+	for _, m := range req.Messages {
+		body.Messages = append(body.Messages, anthropicMessage{Role: m.Role, Content: m.Content})
+	}
+	if req.Schema != nil {
+		body.Tools = []anthropicTool{{
+			Name:        commitMessageToolName,
+			Description: "Record the generated git commit message",
+			InputSchema: marshalSchema(req.Schema),
+		}}
+		body.ToolChoice = &anthropicToolChoice{Type: "tool", Name: commitMessageToolName}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("序列化 Anthropic 请求失败: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.messagesURL(), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("创建 Anthropic 请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Anthropic API 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respData, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("请求 Anthropic API 失败: %w", parseAnthropicError(resp.StatusCode, respData))
+	}
+
+	if req.OnToken != nil {
+		return p.readStream(resp.Body, req.OnToken)
+	}
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取 Anthropic 响应失败: %w", err)
+	}
+
+	var result anthropicResponse
+	if err := json.Unmarshal(respData, &result); err != nil {
+		return nil, fmt.Errorf("解析 Anthropic 响应失败: %w", err)
+	}
+
+	var content strings.Builder
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "tool_use":
+			// 工具调用的参数即结构化的提交信息
+			content.Write(block.Input)
+		}
+	}
+	return &chatResponse{
+		Content: content.String(),
+		Usage: Usage{
+			PromptTokens:     result.Usage.InputTokens,
+			CompletionTokens: result.Usage.OutputTokens,
+			TotalTokens:      result.Usage.InputTokens + result.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+// readStream 解析 Messages API 的 SSE 事件流
+func (p *AnthropicProvider) readStream(r io.Reader, onToken func(string)) (*chatResponse, error) {
+	result := &chatResponse{}
+	var content strings.Builder
+
+	err := readSSE(r, func(data []byte) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("解析 Anthropic 流式响应失败: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			result.Usage.PromptTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			text := event.Delta.Text
+			if event.Delta.Type == "input_json_delta" {
+				text = event.Delta.PartialJSON
+			}
+			if text != "" {
+				content.WriteString(text)
+				onToken(text)
+			}
+		case "message_delta":
+			result.Usage.CompletionTokens = event.Usage.OutputTokens
+		case "error":
+			return fmt.Errorf("Anthropic 流式响应中断: %s: %s", event.Error.Type, event.Error.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Content = content.String()
+	result.Usage.TotalTokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+	return result, nil
+}
+
+// parseAnthropicError 将非 200 响应转换为 AnthropicAPIError
+func parseAnthropicError(statusCode int, data []byte) *AnthropicAPIError {
+	apiErr := &AnthropicAPIError{StatusCode: statusCode}
+	var errResp anthropicErrorResponse
+	if json.Unmarshal(data, &errResp) == nil && errResp.Error.Message != "" {
+		apiErr.Type = errResp.Error.Type
+		apiErr.Message = errResp.Error.Message
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return apiErr
+}
+
+func (p *AnthropicProvider) displayName() string {
+	return "Anthropic"
+}
+
+func (p *AnthropicProvider) modelName() string {
+	return p.model
+}
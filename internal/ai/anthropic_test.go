@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newAnthropicTestServer 创建模拟 Messages API 的测试服务器
+func newAnthropicTestServer(t *testing.T, handler func(t *testing.T, req anthropicRequest) (int, string)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("期望路径 /v1/messages, 实际=%s", r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "test-key" {
+			t.Errorf("期望 x-api-key='test-key', 实际='%s'", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != anthropicVersion {
+			t.Errorf("期望 anthropic-version='%s', 实际='%s'", anthropicVersion, got)
+		}
+
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("解析请求失败: %v", err)
+		}
+
+		status, body := handler(t, req)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+func TestAnthropicGenerateCommitMessage(t *testing.T) {
+	server := newAnthropicTestServer(t, func(t *testing.T, req anthropicRequest) (int, string) {
+		if !strings.Contains(req.System, "You are a helpful assistant") {
+			t.Error("系统提示应放在顶层 system 字段")
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Role != roleUser {
+			t.Errorf("期望 1 条 user 消息, 实际=%+v", req.Messages)
+		}
+		if req.MaxTokens == 0 {
+			t.Error("max_tokens 为必填字段")
+		}
+		return http.StatusOK, `{"content":[{"type":"text","text":"feat(auth): add login\n\n- add login endpoint"}],"stop_reason":"end_turn"}`
+	})
+	defer server.Close()
+
+	p, err := newAnthropicProvider(ProviderConfig{APIKey: "test-key", BaseURL: server.URL, Language: "en"})
+	if err != nil {
+		t.Fatalf("创建 Provider 失败: %v", err)
+	}
+
+	msg, err := p.GenerateCommitMessage(context.Background(), &CommitInfo{
+		FilesChanged: []string{"auth.go"},
+		DiffContent:  "+func Login() {}",
+		BranchName:   "main",
+	})
+	if err != nil {
+		t.Fatalf("生成提交消息失败: %v", err)
+	}
+	if msg.Title != "feat(auth): add login" {
+		t.Errorf("期望 Title='feat(auth): add login', 实际='%s'", msg.Title)
+	}
+	if msg.Body != "- add login endpoint" {
+		t.Errorf("期望 Body='- add login endpoint', 实际='%s'", msg.Body)
+	}
+}
+
+func TestAnthropicAPIError(t *testing.T) {
+	server := newAnthropicTestServer(t, func(t *testing.T, req anthropicRequest) (int, string) {
+		return http.StatusUnauthorized, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`
+	})
+	defer server.Close()
+
+	p, err := newAnthropicProvider(ProviderConfig{APIKey: "test-key", BaseURL: server.URL + "/v1", Language: "en"})
+	if err != nil {
+		t.Fatalf("创建 Provider 失败: %v", err)
+	}
+
+	result := p.Check(context.Background())
+	if result.APIConnected {
+		t.Fatal("期望连接失败")
+	}
+	if !strings.Contains(result.Error.Error(), "invalid x-api-key") {
+		t.Errorf("错误信息应包含 API 返回的原因, 实际='%v'", result.Error)
+	}
+}
+
+func TestNewProvider_Anthropic(t *testing.T) {
+	p, err := NewProvider("anthropic", ProviderConfig{APIKey: "test-key", Language: "en"})
+	if err != nil {
+		t.Fatalf("创建 Provider 失败: %v", err)
+	}
+	ap, ok := p.(*AnthropicProvider)
+	if !ok {
+		t.Fatalf("期望 *AnthropicProvider, 实际=%T", p)
+	}
+	if ap.model != AnthropicDefaultModel {
+		t.Errorf("期望默认模型='%s', 实际='%s'", AnthropicDefaultModel, ap.model)
+	}
+
+	if _, err := NewProvider("anthropic", ProviderConfig{Language: "en"}); err == nil {
+		t.Error("期望缺少 API 密钥时返回错误")
+	}
+}
+
+func TestAnthropicGenerateCommitMessageStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("解析请求失败: %v", err)
+		}
+		if !req.Stream {
+			t.Error("期望流式请求")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{"usage":{"input_tokens":42}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"feat(api): "}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"add stream\n\nbody"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, e := range events {
+			_, _ = w.Write([]byte("event: x\ndata: " + e + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	p, err := newAnthropicProvider(ProviderConfig{APIKey: "test-key", BaseURL: server.URL, Language: "en"})
+	if err != nil {
+		t.Fatalf("创建 Provider 失败: %v", err)
+	}
+
+	var tokens []string
+	msg, err := p.GenerateCommitMessageStream(context.Background(), &CommitInfo{}, func(token string) {
+		tokens = append(tokens, token)
+	})
+	if err != nil {
+		t.Fatalf("流式生成失败: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Errorf("期望收到 2 段文本, 实际=%v", tokens)
+	}
+	if msg.Title != "feat(api): add stream" || msg.Body != "body" {
+		t.Errorf("最终消息解析错误: %+v", msg)
+	}
+	if msg.Usage.PromptTokens != 42 || msg.Usage.CompletionTokens != 7 {
+		t.Errorf("token 用量解析错误: %+v", msg.Usage)
+	}
+}
+
+func TestAnthropicStructuredOutput(t *testing.T) {
+	server := newAnthropicTestServer(t, func(t *testing.T, req anthropicRequest) (int, string) {
+		if len(req.Tools) != 1 || req.ToolChoice == nil || req.ToolChoice.Name != req.Tools[0].Name {
+			t.Errorf("结构化输出应强制调用工具, 实际 tools=%+v tool_choice=%+v", req.Tools, req.ToolChoice)
+		}
+		return http.StatusOK, `{"content":[{"type":"tool_use","id":"t1","name":"commit_message","input":{"type":"feat","scope":"auth","subject":"add login","body":"","breaking":false,"footers":["Refs #12"]}}],"stop_reason":"tool_use"}`
+	})
+	defer server.Close()
+
+	p, err := newAnthropicProvider(ProviderConfig{APIKey: "test-key", BaseURL: server.URL, Language: "en"})
+	if err != nil {
+		t.Fatalf("创建 Provider 失败: %v", err)
+	}
+
+	msg, err := p.GenerateCommitMessage(context.Background(), &CommitInfo{})
+	if err != nil {
+		t.Fatalf("生成提交消息失败: %v", err)
+	}
+	if msg.Title != "feat(auth): add login" || msg.Body != "Refs #12" {
+		t.Errorf("工具调用参数解析错误: %+v", msg)
+	}
+	if msg.Type != "feat" || msg.Scope != "auth" || len(msg.Footers) != 1 {
+		t.Errorf("结构化字段解析错误: %+v", msg)
+	}
+}
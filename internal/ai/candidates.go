@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// GenerateCandidates 并发请求 n 次，生成多条候选提交消息，结果去重并保持请求顺序
+// 部分请求失败时返回成功的候选，全部失败时返回错误
+func GenerateCandidates(ctx context.Context, p Provider, info *CommitInfo, n int) ([]*CommitMessage, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("候选数量必须大于 0: %d", n)
+	}
+
+	results := make([]*CommitMessage, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = p.GenerateCommitMessage(ctx, info)
+		}()
+	}
+	wg.Wait()
+
+	var candidates []*CommitMessage
+	seen := map[string]bool{}
+	for _, msg := range results {
+		if msg == nil {
+			continue
+		}
+		key := msg.Title + "\n\n" + msg.Body
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, msg)
+	}
+
+	if len(candidates) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return candidates, nil
+}
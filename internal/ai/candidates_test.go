@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// sequenceProvider 按调用顺序返回预设的标题，空字符串或超出列表时返回错误
+type sequenceProvider struct {
+	fakeProvider
+	titles []string
+	calls  atomic.Int32
+}
+
+func (s *sequenceProvider) GenerateCommitMessage(ctx context.Context, info *CommitInfo) (*CommitMessage, error) {
+	i := int(s.calls.Add(1)) - 1
+	if i >= len(s.titles) || s.titles[i] == "" {
+		return nil, fmt.Errorf("call %d failed", i)
+	}
+	return &CommitMessage{Title: s.titles[i]}, nil
+}
+
+func TestGenerateCandidates(t *testing.T) {
+	p := &sequenceProvider{titles: []string{"feat: a", "feat: b", "feat: a"}}
+	candidates, err := GenerateCandidates(context.Background(), p, &CommitInfo{}, 3)
+	if err != nil {
+		t.Fatalf("生成候选失败: %v", err)
+	}
+	if p.calls.Load() != 3 {
+		t.Errorf("期望请求 3 次, 实际=%d", p.calls.Load())
+	}
+	if len(candidates) != 2 {
+		t.Errorf("重复的候选应被去掉, 实际=%d 条", len(candidates))
+	}
+}
+
+func TestGenerateCandidates_PartialFailure(t *testing.T) {
+	p := &sequenceProvider{titles: []string{"fix: only one"}}
+	candidates, err := GenerateCandidates(context.Background(), p, &CommitInfo{}, 3)
+	if err != nil {
+		t.Fatalf("部分失败时应返回成功的候选, 实际错误=%v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Title != "fix: only one" {
+		t.Errorf("期望 1 条候选, 实际=%v", candidates)
+	}
+}
+
+func TestGenerateCandidates_AllFail(t *testing.T) {
+	p := &sequenceProvider{}
+	if _, err := GenerateCandidates(context.Background(), p, &CommitInfo{}, 2); err == nil {
+		t.Error("全部失败时应返回错误")
+	}
+	if _, err := GenerateCandidates(context.Background(), &fakeProvider{}, &CommitInfo{}, 0); err == nil {
+		t.Error("候选数量为 0 时应返回错误")
+	}
+}
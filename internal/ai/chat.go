@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SimonGino/aicommit/internal/lint"
+)
+
+// 对话角色，与各家 API 的角色命名保持一致
+const (
+	roleUser      = "user"
+	roleAssistant = "assistant"
+)
+
+// chatMessage 表示一轮对话消息
+type chatMessage struct {
+	Role    string
+	Content string
+}
+
+// chatRequest 描述一次与模型的对话请求，与具体提供商的协议无关
+type chatRequest struct {
+	System      string
+	Messages    []chatMessage
+	Temperature float32
+	MaxTokens   int
+	// Schema 非空时要求模型返回符合该 schema 的 JSON，各提供商使用各自支持的方式（response_format、工具调用等）
+	Schema *jsonSchema
+	// OnToken 非空时以流式方式请求，每收到一段文本回调一次
+	OnToken func(token string)
+}
+
+// chatResponse 表示模型返回的文本及 token 用量
+type chatResponse struct {
+	Content string
+	Usage   Usage
+}
+
+// chatClient 由各提供商实现，负责把 chatRequest 翻译为自身的 API 调用
+type chatClient interface {
+	// chat 发送请求并返回模型生成的文本
+	chat(ctx context.Context, req *chatRequest) (*chatResponse, error)
+	// displayName 返回用于错误提示的提供商名称
+	displayName() string
+	// modelName 返回请求使用的模型
+	modelName() string
+}
+
+// generateCommitMessage 使用统一的提示词流程生成提交消息
+// diff 超出预算时按策略截断或分块总结；onToken 非空时以流式方式生成，最终结果与非流式一致
+func generateCommitMessage(ctx context.Context, c chatClient, style commitStyle, info *CommitInfo, policy DiffPolicy, onToken func(string)) (*CommitMessage, error) {
+	var warning string
+	if policy.Mode == DiffModeSummarize {
+		if tokens := policy.count(info.DiffContent); tokens > policy.Budget {
+			// 只有一个文件时总结与截断等价，不必多发请求；块数过多时同样改为截断，并提示用户
+			chunks := splitDiff(info.DiffContent, policy.byteBudget(info.DiffContent, tokens))
+			if len(chunks) > MaxSummaryChunks {
+				warning = fmt.Sprintf("diff 分为 %d 块，超过分块总结的上限 %d，已改为截断，超出预算的内容未发送给模型", len(chunks), MaxSummaryChunks)
+			} else if len(chunks) > 1 {
+				return summarizeCommitMessage(ctx, c, style, info, chunks, policy, onToken)
+			}
+		}
+	}
+
+	// 截断过长的 diff 内容
+	truncatedInfo := &CommitInfo{
+		FilesChanged: info.FilesChanged,
+		DiffContent:  policy.truncate(info.DiffContent),
+		BranchName:   info.BranchName,
+	}
+	message, err := requestCommitMessage(ctx, c, style, truncatedInfo, onToken)
+	if err != nil {
+		return nil, err
+	}
+	if warning != "" {
+		message.Warnings = append(message.Warnings, warning)
+	}
+	return message, nil
+}
+
+// requestCommitMessage 把已经控制在预算内的信息发送给模型并解析提交消息
+func requestCommitMessage(ctx context.Context, c chatClient, style commitStyle, info *CommitInfo, onToken func(string)) (*CommitMessage, error) {
+	return continueConversation(ctx, c, style, []chatMessage{
+		{Role: roleUser, Content: userPrompt(style.language, info, buildFilesList(info.FilesChanged))},
+	}, onToken)
+}
+
+// refineCommitMessage 把之前的提交消息作为模型的回复，追加用户反馈后继续对话
+func refineCommitMessage(ctx context.Context, c chatClient, style commitStyle, previous *CommitMessage, feedback string, onToken func(string)) (*CommitMessage, error) {
+	if previous == nil || len(previous.conversation) == 0 {
+		return nil, fmt.Errorf("提交消息不是由模型生成的，无法根据反馈修改")
+	}
+	if strings.TrimSpace(feedback) == "" {
+		return nil, fmt.Errorf("反馈内容不能为空")
+	}
+
+	messages := slices.Clone(previous.conversation)
+	messages = append(messages, chatMessage{Role: roleUser, Content: refinePrompt(style.language, feedback)})
+	return continueConversation(ctx, c, style, messages, onToken)
+}
+
+// maxLintRetries 提交信息自动修复后仍不符合规范时，要求模型修正的最大次数
+const maxLintRetries = 1
+
+// continueConversation 发送对话并解析提交消息
+// 提交信息不符合规范时先自动修复，仍有错误时把问题反馈给模型修正；修正失败时返回修复后的原消息
+func continueConversation(ctx context.Context, c chatClient, style commitStyle, messages []chatMessage, onToken func(string)) (*CommitMessage, error) {
+	message, err := converse(ctx, c, style, messages, onToken)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := lint.DefaultRules(style.typeNames())
+	for attempt := 0; ; attempt++ {
+		issues := message.fixLint(rules)
+		if !lint.HasErrors(issues) || attempt >= maxLintRetries {
+			return message, nil
+		}
+
+		retryMessages := slices.Clone(message.conversation)
+		retryMessages = append(retryMessages, chatMessage{Role: roleUser, Content: lintFeedbackPrompt(style.language, issues)})
+		// 修正结果不再实时显示，避免与已显示的内容混在一起
+		revised, err := converse(ctx, c, style, retryMessages, nil)
+		if err != nil {
+			return message, nil
+		}
+		revised.Usage = message.Usage.add(revised.Usage)
+		message = revised
+	}
+}
+
+// converse 发送一轮对话并解析模型回复的提交消息，回复会追加到消息的对话记录中
+func converse(ctx context.Context, c chatClient, style commitStyle, messages []chatMessage, onToken func(string)) (*CommitMessage, error) {
+	resp, err := c.chat(ctx, &chatRequest{
+		System:      systemPrompt(style),
+		Messages:    messages,
+		Temperature: 0.7,
+		MaxTokens:   1500,
+		Schema:      commitMessageSchema(style),
+		OnToken:     newStructuredStream(onToken),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Content == "" {
+		return nil, fmt.Errorf("%s 未返回有效的提交信息内容", c.displayName())
+	}
+
+	message, err := parseCommitMessage(resp.Content)
+	if err != nil {
+		return nil, fmt.Errorf("%s 返回的提交信息无效: %w", c.displayName(), err)
+	}
+	message.Usage = resp.Usage
+	message.Model = c.modelName()
+	message.conversation = append(messages, chatMessage{Role: roleAssistant, Content: resp.Content})
+	return message, nil
+}
+
+// fixLint 自动修复提交信息中可以修复的问题，返回仍然存在的问题
+func (m *CommitMessage) fixLint(rules lint.Rules) []lint.Issue {
+	text := m.Text()
+	fixed, issues := lint.Fix(text, rules)
+	if fixed != text {
+		if parsed, err := parseCommitMessage(fixed); err == nil {
+			m.Title, m.Body = parsed.Title, parsed.Body
+			m.Type, m.Scope, m.Subject = parsed.Type, parsed.Scope, parsed.Subject
+			m.Breaking, m.Footers = parsed.Breaking, parsed.Footers
+		}
+	}
+	return issues
+}
+
+// generateDailyReport 使用统一的提示词流程生成日报
+func generateDailyReport(ctx context.Context, c chatClient, language string, info *ReportInfo, since, until string) (string, error) {
+	resp, err := c.chat(ctx, &chatRequest{
+		Messages: []chatMessage{
+			{Role: roleUser, Content: reportPrompt(language, info, since, until)},
+		},
+		Temperature: 0.7,
+		MaxTokens:   2000,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if resp.Content == "" {
+		return "", fmt.Errorf("%s 未返回有效的日报内容", c.displayName())
+	}
+
+	return cleanMarkdownFormatting(resp.Content), nil
+}
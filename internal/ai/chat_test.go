@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// replyClient 按顺序返回预设的回复，并记录收到的请求
+type replyClient struct {
+	replies  []string
+	requests []*chatRequest
+}
+
+func (c *replyClient) chat(ctx context.Context, req *chatRequest) (*chatResponse, error) {
+	reply := c.replies[len(c.requests)]
+	c.requests = append(c.requests, req)
+	return &chatResponse{Content: reply}, nil
+}
+
+func (c *replyClient) displayName() string { return "reply" }
+
+func (c *replyClient) modelName() string { return "reply-model" }
+
+func TestRefineCommitMessage(t *testing.T) {
+	client := &replyClient{replies: []string{
+		"feat: add login\n\nlong body",
+		"feat(auth): add login",
+		"feat(auth): add login endpoint",
+	}}
+	info := &CommitInfo{DiffContent: "diff --git a/x b/x\n"}
+
+	first, err := generateCommitMessage(context.Background(), client, commitStyle{language: "en"}, info, DiffPolicy{Budget: 1000}, nil)
+	if err != nil {
+		t.Fatalf("生成失败: %v", err)
+	}
+	refined, err := refineCommitMessage(context.Background(), client, commitStyle{language: "en"}, first, "scope should be auth", nil)
+	if err != nil {
+		t.Fatalf("修改失败: %v", err)
+	}
+	if refined.Title != "feat(auth): add login" || refined.Body != "" {
+		t.Errorf("期望返回修改后的消息, 实际=%+v", refined)
+	}
+
+	req := client.requests[1]
+	if len(req.Messages) != 3 {
+		t.Fatalf("期望 用户/模型/反馈 3 条消息, 实际=%d", len(req.Messages))
+	}
+	if req.Messages[1].Role != roleAssistant || req.Messages[1].Content != "feat: add login\n\nlong body" {
+		t.Errorf("之前的消息应作为模型的回复, 实际=%+v", req.Messages[1])
+	}
+	if req.Messages[2].Role != roleUser || !strings.Contains(req.Messages[2].Content, "scope should be auth") {
+		t.Errorf("最后一条应为用户反馈, 实际=%+v", req.Messages[2])
+	}
+
+	// 继续修改时保留完整的对话
+	if _, err := refineCommitMessage(context.Background(), client, commitStyle{language: "en"}, refined, "mention the endpoint", nil); err != nil {
+		t.Fatalf("再次修改失败: %v", err)
+	}
+	if n := len(client.requests[2].Messages); n != 5 {
+		t.Errorf("期望 5 条消息, 实际=%d", n)
+	}
+}
+
+func TestRefineCommitMessage_Invalid(t *testing.T) {
+	client := &replyClient{}
+	if _, err := refineCommitMessage(context.Background(), client, commitStyle{language: "en"}, &CommitMessage{Title: "feat: manual"}, "shorter", nil); err == nil {
+		t.Error("不是由模型生成的消息应返回错误")
+	}
+	previous := &CommitMessage{Title: "feat: x", conversation: []chatMessage{{Role: roleUser, Content: "diff"}, {Role: roleAssistant, Content: "feat: x"}}}
+	if _, err := refineCommitMessage(context.Background(), client, commitStyle{language: "en"}, previous, "  ", nil); err == nil {
+		t.Error("空反馈应返回错误")
+	}
+	if len(client.requests) != 0 {
+		t.Errorf("参数无效时不应发送请求, 实际=%d", len(client.requests))
+	}
+}
+
+func TestGenerateCommitMessage_LintFix(t *testing.T) {
+	client := &replyClient{replies: []string{"Feat( api ):add login."}}
+	msg, err := generateCommitMessage(context.Background(), client, commitStyle{language: "en"}, &CommitInfo{}, DiffPolicy{Budget: 1000}, nil)
+	if err != nil {
+		t.Fatalf("生成失败: %v", err)
+	}
+	if msg.Title != "feat(api): add login" || msg.Type != "feat" || msg.Scope != "api" {
+		t.Errorf("可以自动修复的问题应直接修复, 实际=%+v", msg)
+	}
+	if msg.Model != "reply-model" {
+		t.Errorf("应记录生成消息的模型, 实际=%q", msg.Model)
+	}
+	if len(client.requests) != 1 {
+		t.Errorf("自动修复后不应重新请求, 实际=%d", len(client.requests))
+	}
+}
+
+func TestGenerateCommitMessage_LintRetry(t *testing.T) {
+	client := &replyClient{replies: []string{
+		"perf: make the login endpoint considerably faster for every single user",
+		"feat: speed up login",
+	}}
+	msg, err := generateCommitMessage(context.Background(), client, commitStyle{language: "en"}, &CommitInfo{}, DiffPolicy{Budget: 1000}, nil)
+	if err != nil {
+		t.Fatalf("生成失败: %v", err)
+	}
+	if msg.Title != "feat: speed up login" {
+		t.Errorf("期望使用修正后的消息, 实际=%q", msg.Title)
+	}
+	if len(client.requests) != 2 {
+		t.Fatalf("期望重新请求 1 次, 实际请求数=%d", len(client.requests))
+	}
+	feedback := client.requests[1].Messages[2].Content
+	if !strings.Contains(feedback, "perf") || !strings.Contains(feedback, "50") {
+		t.Errorf("反馈应列出违反的规则, 实际=%q", feedback)
+	}
+}
+
+func TestGenerateCommitMessage_CustomTypes(t *testing.T) {
+	client := &replyClient{replies: []string{"perf: speed up login"}}
+	style := commitStyle{language: "en", types: []string{"feat", "perf"}}
+	msg, err := generateCommitMessage(context.Background(), client, style, &CommitInfo{}, DiffPolicy{Budget: 1000}, nil)
+	if err != nil {
+		t.Fatalf("生成失败: %v", err)
+	}
+	if msg.Title != "perf: speed up login" || len(client.requests) != 1 {
+		t.Errorf("配置的类型应通过检查, 实际=%q, 请求数=%d", msg.Title, len(client.requests))
+	}
+
+	req := client.requests[0]
+	if !strings.Contains(req.System, "- feat: New feature\n- perf\n") || strings.Contains(req.System, "- fix") {
+		t.Errorf("系统提示应只列出配置的类型: %q", req.System)
+	}
+	if enum := req.Schema.Properties[0].Schema.Enum; strings.Join(enum, ",") != "feat,perf" {
+		t.Errorf("schema 应只允许配置的类型, 实际=%v", enum)
+	}
+}
@@ -2,40 +2,90 @@ package ai
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
-
-	openai "github.com/sashabaranov/go-openai"
 )
 
 // CheckResult 配置检测结果
 type CheckResult struct {
 	ConfigExists     bool
 	APIKeyConfigured bool
+	APIKeyOptional   bool // 提供商无需 API Key（如本地 Ollama）
 	APIKeyMasked     string
 	Provider         string
 	Model            string
+	Models           []string // 服务端可用的模型列表（如支持）
 	BaseURL          string
 	APIConnected     bool
 	ResponseTime     time.Duration
 	Error            error
 }
 
+// Passed 判断所有检查是否通过
+func (r *CheckResult) Passed() bool {
+	return r.APIConnected && r.Error == nil
+}
+
+// MarshalJSON 序列化检测结果，错误转换为文本，响应时间以毫秒表示
+func (r *CheckResult) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Passed           bool     `json:"passed"`
+		ConfigExists     bool     `json:"config_exists"`
+		APIKeyConfigured bool     `json:"api_key_configured"`
+		APIKeyOptional   bool     `json:"api_key_optional"`
+		APIKeyMasked     string   `json:"api_key_masked,omitempty"`
+		Provider         string   `json:"provider"`
+		Model            string   `json:"model"`
+		Models           []string `json:"models,omitempty"`
+		BaseURL          string   `json:"base_url,omitempty"`
+		APIConnected     bool     `json:"api_connected"`
+		ResponseTimeMs   int64    `json:"response_time_ms"`
+		Error            string   `json:"error,omitempty"`
+	}{
+		Passed:           r.Passed(),
+		ConfigExists:     r.ConfigExists,
+		APIKeyConfigured: r.APIKeyConfigured,
+		APIKeyOptional:   r.APIKeyOptional,
+		APIKeyMasked:     r.APIKeyMasked,
+		Provider:         r.Provider,
+		Model:            r.Model,
+		Models:           r.Models,
+		BaseURL:          r.BaseURL,
+		APIConnected:     r.APIConnected,
+		ResponseTimeMs:   r.ResponseTime.Milliseconds(),
+	}
+	if r.Error != nil {
+		out.Error = r.Error.Error()
+	}
+	return json.Marshal(out)
+}
+
+// Checker 由支持连通性检测的 Provider 实现
+type Checker interface {
+	Check(ctx context.Context) *CheckResult
+}
+
 // Check 测试 API 连通性
 // 发送一个简单的请求来验证 API Key 和网络连接
 func (p *OpenAIProvider) Check(ctx context.Context) *CheckResult {
-	result := &CheckResult{
-		ConfigExists:     true, // 如果能到这里，配置已存在
-		APIKeyConfigured: p.apiKey != "",
-		Provider:         p.provider,
-		Model:            p.model,
-		BaseURL:          p.baseURL,
-	}
+	return runCheck(ctx, p, &CheckResult{
+		Provider: p.provider,
+		Model:    p.model,
+		BaseURL:  p.baseURL,
+	}, p.apiKey)
+}
+
+// runCheck 使用 chatClient 发送测试请求，填充并返回检测结果
+func runCheck(ctx context.Context, c chatClient, result *CheckResult, apiKey string) *CheckResult {
+	result.ConfigExists = true // 如果能到这里，配置已存在
+	result.APIKeyConfigured = apiKey != ""
 
 	// 掩码 API Key
-	if len(p.apiKey) > 8 {
-		result.APIKeyMasked = p.apiKey[:4] + "..." + p.apiKey[len(p.apiKey)-4:]
-	} else if p.apiKey != "" {
+	if len(apiKey) > 8 {
+		result.APIKeyMasked = apiKey[:4] + "..." + apiKey[len(apiKey)-4:]
+	} else if apiKey != "" {
 		result.APIKeyMasked = "***"
 	}
 
@@ -46,19 +96,12 @@ func (p *OpenAIProvider) Check(ctx context.Context) *CheckResult {
 
 	// 发送测试请求
 	start := time.Now()
-	_, err := p.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: p.model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: "Hi",
-				},
-			},
-			MaxTokens: 5,
+	_, err := c.chat(ctx, &chatRequest{
+		Messages: []chatMessage{
+			{Role: roleUser, Content: "Hi"},
 		},
-	)
+		MaxTokens: 5,
+	})
 
 	result.ResponseTime = time.Since(start)
 
@@ -78,18 +121,28 @@ func PrintCheckResult(result *CheckResult) {
 
 	// 配置状态
 	printStatus("配置文件", result.ConfigExists, "")
-	printStatus("API Key", result.APIKeyConfigured, result.APIKeyMasked)
+	if result.APIKeyOptional {
+		printStatus("API Key", true, "无需配置")
+	} else {
+		printStatus("API Key", result.APIKeyConfigured, result.APIKeyMasked)
+	}
 	printStatus("Provider", true, result.Provider)
 	printStatus("Model", true, result.Model)
 	if result.BaseURL != "" {
 		printStatus("Base URL", true, result.BaseURL)
 	}
+	if len(result.Models) > 0 {
+		printStatus("可用模型", true, strings.Join(result.Models, ", "))
+	}
 
 	fmt.Println()
 
 	// API 连通性
 	if result.APIConnected {
 		fmt.Printf("✓ API 连接: \033[32m成功\033[0m (%dms)\n", result.ResponseTime.Milliseconds())
+		if result.Error != nil {
+			fmt.Printf("✗ %v\n", result.Error)
+		}
 	} else if result.Error != nil {
 		fmt.Printf("✗ API 连接: \033[31m失败\033[0m\n")
 		fmt.Printf("  错误: %v\n", result.Error)
@@ -97,7 +150,7 @@ func PrintCheckResult(result *CheckResult) {
 
 	fmt.Println()
 
-	if result.APIConnected {
+	if result.Passed() {
 		fmt.Println("\033[32m所有检查通过 ✅\033[0m")
 	} else {
 		fmt.Println("\033[31m检查未通过 ❌\033[0m")
@@ -0,0 +1,35 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckResult_MarshalJSON(t *testing.T) {
+	result := &CheckResult{
+		ConfigExists:     true,
+		APIKeyConfigured: true,
+		APIKeyMasked:     "sk-1...abcd",
+		Provider:         "openai",
+		Model:            "gpt-4o",
+		ResponseTime:     1500 * time.Millisecond,
+		Error:            errors.New("API 连接失败: timeout"),
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if decoded["passed"] != false || decoded["error"] != "API 连接失败: timeout" || decoded["response_time_ms"] != float64(1500) {
+		t.Errorf("序列化结果错误: %s", data)
+	}
+	if _, ok := decoded["base_url"]; ok {
+		t.Errorf("空字段应省略: %s", data)
+	}
+}
@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// NamedProvider 带名称的提供商，名称用于提示用户实际使用的后端
+type NamedProvider struct {
+	Name     string
+	Provider Provider
+}
+
+// FallbackProvider 按顺序尝试多个提供商，前一个失败（鉴权、网络、配额等）时自动切换到下一个
+type FallbackProvider struct {
+	providers []NamedProvider
+	// OnFallback 切换到下一个提供商前调用，可用于提示用户
+	OnFallback func(failed string, err error, next string)
+}
+
+// NewFallbackProvider 创建故障转移提供商，providers 按优先级排列
+func NewFallbackProvider(providers ...NamedProvider) *FallbackProvider {
+	return &FallbackProvider{providers: providers}
+}
+
+// Names 返回故障转移链中的提供商名称
+func (f *FallbackProvider) Names() []string {
+	names := make([]string, len(f.providers))
+	for i, np := range f.providers {
+		names[i] = np.Name
+	}
+	return names
+}
+
+// GenerateCommitMessage 依次尝试各提供商生成提交消息
+func (f *FallbackProvider) GenerateCommitMessage(ctx context.Context, info *CommitInfo) (*CommitMessage, error) {
+	return f.generate(ctx, func(p Provider) (*CommitMessage, error) {
+		return p.GenerateCommitMessage(ctx, info)
+	})
+}
+
+// GenerateCommitMessageStream 依次尝试各提供商流式生成提交消息
+func (f *FallbackProvider) GenerateCommitMessageStream(ctx context.Context, info *CommitInfo, onToken func(token string)) (*CommitMessage, error) {
+	return f.generate(ctx, func(p Provider) (*CommitMessage, error) {
+		return p.GenerateCommitMessageStream(ctx, info, onToken)
+	})
+}
+
+// RefineCommitMessage 依次尝试各提供商根据反馈修改提交消息
+func (f *FallbackProvider) RefineCommitMessage(ctx context.Context, previous *CommitMessage, feedback string, onToken func(token string)) (*CommitMessage, error) {
+	return f.generate(ctx, func(p Provider) (*CommitMessage, error) {
+		return p.RefineCommitMessage(ctx, previous, feedback, onToken)
+	})
+}
+
+// GenerateDailyReport 依次尝试各提供商生成日报
+func (f *FallbackProvider) GenerateDailyReport(ctx context.Context, info *ReportInfo, since, until string) (string, error) {
+	var report string
+	_, err := f.try(ctx, func(p Provider) error {
+		var err error
+		report, err = p.GenerateDailyReport(ctx, info, since, until)
+		return err
+	})
+	return report, err
+}
+
+func (f *FallbackProvider) generate(ctx context.Context, fn func(Provider) (*CommitMessage, error)) (*CommitMessage, error) {
+	var message *CommitMessage
+	name, err := f.try(ctx, func(p Provider) error {
+		var err error
+		message, err = fn(p)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	message.Provider = name
+	return message, nil
+}
+
+// try 依次调用 fn 直到成功，返回成功的提供商名称
+// 用户取消（context 结束）时不再尝试后续提供商
+func (f *FallbackProvider) try(ctx context.Context, fn func(Provider) error) (string, error) {
+	if len(f.providers) == 0 {
+		return "", fmt.Errorf("未配置任何提供商")
+	}
+
+	var errs []error
+	for i, np := range f.providers {
+		err := fn(np.Provider)
+		if err == nil {
+			return np.Name, nil
+		}
+		if len(f.providers) == 1 {
+			return "", err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", np.Name, err))
+
+		if ctx.Err() != nil {
+			break
+		}
+		if i+1 < len(f.providers) && f.OnFallback != nil {
+			f.OnFallback(np.Name, err, f.providers[i+1].Name)
+		}
+	}
+	return "", fmt.Errorf("所有提供商均调用失败: %w", errors.Join(errs...))
+}
@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// failingProvider 总是返回错误的 Provider
+type failingProvider struct {
+	fakeProvider
+	err   error
+	calls int
+}
+
+func (f *failingProvider) GenerateCommitMessage(ctx context.Context, info *CommitInfo) (*CommitMessage, error) {
+	f.calls++
+	return nil, f.err
+}
+
+func (f *failingProvider) GenerateCommitMessageStream(ctx context.Context, info *CommitInfo, onToken func(string)) (*CommitMessage, error) {
+	return f.GenerateCommitMessage(ctx, info)
+}
+
+func (f *failingProvider) GenerateDailyReport(ctx context.Context, info *ReportInfo, since, until string) (string, error) {
+	f.calls++
+	return "", f.err
+}
+
+func TestFallbackProvider_UsesNextOnFailure(t *testing.T) {
+	primary := &failingProvider{err: errors.New("401 unauthorized")}
+	fp := NewFallbackProvider(
+		NamedProvider{Name: "azure", Provider: primary},
+		NamedProvider{Name: "ollama", Provider: &fakeProvider{}},
+	)
+
+	var notified []string
+	fp.OnFallback = func(failed string, err error, next string) {
+		notified = append(notified, failed+"->"+next)
+	}
+
+	msg, err := fp.GenerateCommitMessage(context.Background(), &CommitInfo{})
+	if err != nil {
+		t.Fatalf("期望切换到备用提供商后成功, 实际错误=%v", err)
+	}
+	if msg.Provider != "ollama" {
+		t.Errorf("期望 Provider='ollama', 实际='%s'", msg.Provider)
+	}
+	if primary.calls != 1 {
+		t.Errorf("期望主提供商被调用 1 次, 实际=%d", primary.calls)
+	}
+	if strings.Join(notified, ",") != "azure->ollama" {
+		t.Errorf("期望通知 azure->ollama, 实际=%v", notified)
+	}
+}
+
+func TestFallbackProvider_PrimarySucceeds(t *testing.T) {
+	backup := &failingProvider{err: errors.New("unused")}
+	fp := NewFallbackProvider(
+		NamedProvider{Name: "openai", Provider: &fakeProvider{}},
+		NamedProvider{Name: "backup", Provider: backup},
+	)
+
+	msg, err := fp.GenerateCommitMessage(context.Background(), &CommitInfo{})
+	if err != nil {
+		t.Fatalf("生成提交消息失败: %v", err)
+	}
+	if msg.Provider != "openai" || backup.calls != 0 {
+		t.Errorf("主提供商成功时不应调用备用提供商, Provider='%s', calls=%d", msg.Provider, backup.calls)
+	}
+}
+
+func TestFallbackProvider_AllFail(t *testing.T) {
+	fp := NewFallbackProvider(
+		NamedProvider{Name: "azure", Provider: &failingProvider{err: errors.New("quota exceeded")}},
+		NamedProvider{Name: "openai", Provider: &failingProvider{err: errors.New("network down")}},
+	)
+
+	_, err := fp.GenerateDailyReport(context.Background(), &ReportInfo{}, "", "")
+	if err == nil {
+		t.Fatal("期望返回错误")
+	}
+	for _, want := range []string{"azure: quota exceeded", "openai: network down"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("错误信息应包含 '%s', 实际=%v", want, err)
+		}
+	}
+}
+
+func TestFallbackProvider_StopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	backup := &failingProvider{err: errors.New("unused")}
+	fp := NewFallbackProvider(
+		NamedProvider{Name: "openai", Provider: &failingProvider{err: context.Canceled}},
+		NamedProvider{Name: "backup", Provider: backup},
+	)
+
+	if _, err := fp.GenerateCommitMessage(ctx, &CommitInfo{}); err == nil {
+		t.Fatal("期望返回错误")
+	}
+	if backup.calls != 0 {
+		t.Errorf("取消后不应尝试备用提供商, 实际调用=%d", backup.calls)
+	}
+}
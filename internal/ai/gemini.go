@@ -0,0 +1,321 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// GeminiDefaultBaseURL Gemini API 的默认地址
+	GeminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	// GeminiDefaultModel Gemini 的默认模型
+	GeminiDefaultModel = "gemini-2.5-flash"
+)
+
+// GeminiProvider 使用 Google Gemini generateContent API 实现 Provider
+type GeminiProvider struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	language   string
+	types      []string // 允许的提交类型，为空时使用默认类型
+	httpClient *http.Client
+	retry      RetryPolicy
+	diff       DiffPolicy
+}
+
+// GeminiAPIError 表示 Gemini API 返回的错误
+type GeminiAPIError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *GeminiAPIError) Error() string {
+	if e.Status != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Status, e.Message)
+	}
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// geminiPart 内容片段
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiContent 一轮对话内容，role 为 "user" 或 "model"
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiGenerationConfig 生成参数
+type geminiGenerationConfig struct {
+	Temperature     float32 `json:"temperature,omitempty"`
+	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+	// ResponseMimeType 和 ResponseSchema 用于结构化输出
+	ResponseMimeType string      `json:"responseMimeType,omitempty"`
+	ResponseSchema   *jsonSchema `json:"responseSchema,omitempty"`
+}
+
+// geminiRequest generateContent 请求体
+type geminiRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+// geminiResponse generateContent 响应体
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiErrorResponse 错误响应体
+type geminiErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// geminiBlockedReasons 表示内容被拦截、不会返回文本的结束原因
+var geminiBlockedReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+}
+
+func init() {
+	Register("gemini", Factory{
+		DisplayName:  "Gemini",
+		Required:     []ConfigField{FieldAPIKey},
+		DefaultModel: GeminiDefaultModel,
+		APIKeyEnv:    "GEMINI_API_KEY",
+		New: func(cfg ProviderConfig) (Provider, error) {
+			return newGeminiProvider(cfg)
+		},
+	})
+}
+
+// newGeminiProvider 创建 Gemini Provider 实例
+func newGeminiProvider(cfg ProviderConfig) (*GeminiProvider, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("Gemini API 密钥不能为空")
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = GeminiDefaultBaseURL
+	}
+	model := cfg.Model
+	if model == "" {
+		model = GeminiDefaultModel
+	}
+
+	return &GeminiProvider{
+		apiKey:     cfg.APIKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      strings.TrimPrefix(model, "models/"),
+		language:   cfg.Language,
+		types:      cfg.CommitTypes,
+		httpClient: newHTTPClient(proxyTransport()),
+		retry:      retryPolicyFrom(cfg),
+		diff:       diffPolicyFrom(cfg),
+	}, nil
+}
+
+// GenerateCommitMessage 使用 Gemini API 生成提交消息
+func (p *GeminiProvider) GenerateCommitMessage(ctx context.Context, info *CommitInfo) (*CommitMessage, error) {
+	return generateCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, info, p.diff, nil)
+}
+
+// GenerateCommitMessageStream 使用 Gemini API 流式生成提交消息
+func (p *GeminiProvider) GenerateCommitMessageStream(ctx context.Context, info *CommitInfo, onToken func(token string)) (*CommitMessage, error) {
+	return generateCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, info, p.diff, onToken)
+}
+
+// RefineCommitMessage 根据用户反馈修改提交消息
+func (p *GeminiProvider) RefineCommitMessage(ctx context.Context, previous *CommitMessage, feedback string, onToken func(token string)) (*CommitMessage, error) {
+	return refineCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, previous, feedback, onToken)
+}
+
+// GenerateDailyReport 使用 Gemini API 生成日报
+func (p *GeminiProvider) GenerateDailyReport(ctx context.Context, info *ReportInfo, since, until string) (string, error) {
+	return generateDailyReport(ctx, withRetry(p, p.retry), p.language, info, since, until)
+}
+
+// Check 测试 Gemini API 连通性
+func (p *GeminiProvider) Check(ctx context.Context) *CheckResult {
+	return runCheck(ctx, p, &CheckResult{
+		Provider: "gemini",
+		Model:    p.model,
+		BaseURL:  p.baseURL,
+	}, p.apiKey)
+}
+
+// chat 将通用请求转换为 Gemini generateContent 请求
+func (p *GeminiProvider) chat(ctx context.Context, req *chatRequest) (*chatResponse, error) {
+	body := geminiRequest{
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:     req.Temperature,
+			MaxOutputTokens: req.MaxTokens,
+		},
+	}
+	if req.Schema != nil {
+		body.GenerationConfig.ResponseMimeType = "application/json"
+		body.GenerationConfig.ResponseSchema = geminiSchema(req.Schema)
+	}
+	if req.System != "" {
+		body.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: req.System}}}
+	}
+	for _, m := range req.Messages {
+		role := m.Role
+		if role == roleAssistant {
+			role = "model"
+		}
+		body.Contents = append(body.Contents, geminiContent{Role: role, Parts: []geminiPart{{Text: m.Content}}})
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("序列化 Gemini 请求失败: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", p.baseURL, p.model)
+	if req.OnToken != nil {
+		endpoint = fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", p.baseURL, p.model)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("创建 Gemini 请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Gemini API 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respData, _ := io.ReadAll(resp.Body)
+		apiErr := &GeminiAPIError{StatusCode: resp.StatusCode}
+		var errResp geminiErrorResponse
+		if json.Unmarshal(respData, &errResp) == nil && errResp.Error.Message != "" {
+			apiErr.Status = errResp.Error.Status
+			apiErr.Message = errResp.Error.Message
+		} else {
+			apiErr.Message = strings.TrimSpace(string(respData))
+		}
+		return nil, fmt.Errorf("请求 Gemini API 失败: %w", apiErr)
+	}
+
+	result := &chatResponse{}
+	var content strings.Builder
+	var finishReason string
+	hasCandidates := false
+
+	// 非流式响应是单个 geminiResponse，流式响应是一串 geminiResponse
+	handle := func(data []byte) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("解析 Gemini 响应失败: %w", err)
+		}
+
+		// 提示词本身被拦截时不会返回任何候选
+		if reason := chunk.PromptFeedback.BlockReason; reason != "" {
+			return fmt.Errorf("Gemini 拒绝了请求（blockReason: %s），diff 中可能包含触发安全策略的内容", reason)
+		}
+		if len(chunk.Candidates) > 0 {
+			hasCandidates = true
+			candidate := chunk.Candidates[0]
+			for _, part := range candidate.Content.Parts {
+				if part.Text == "" {
+					continue
+				}
+				content.WriteString(part.Text)
+				if req.OnToken != nil {
+					req.OnToken(part.Text)
+				}
+			}
+			if candidate.FinishReason != "" {
+				finishReason = candidate.FinishReason
+			}
+		} else if req.OnToken == nil {
+			return fmt.Errorf("Gemini 未返回任何候选结果")
+		}
+
+		if chunk.UsageMetadata.TotalTokenCount > 0 {
+			result.Usage = Usage{
+				PromptTokens:     chunk.UsageMetadata.PromptTokenCount,
+				CompletionTokens: chunk.UsageMetadata.CandidatesTokenCount,
+				TotalTokens:      chunk.UsageMetadata.TotalTokenCount,
+			}
+		}
+		return nil
+	}
+
+	if req.OnToken != nil {
+		err = readSSE(resp.Body, handle)
+	} else {
+		var respData []byte
+		respData, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("读取 Gemini 响应失败: %w", err)
+		}
+		err = handle(respData)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if content.Len() == 0 {
+		return nil, geminiEmptyError(finishReason, hasCandidates)
+	}
+
+	result.Content = content.String()
+	return result, nil
+}
+
+// geminiEmptyError 说明 Gemini 没有返回文本的原因
+func geminiEmptyError(finishReason string, hasCandidates bool) error {
+	switch {
+	case !hasCandidates:
+		return fmt.Errorf("Gemini 未返回任何候选结果")
+	case geminiBlockedReasons[finishReason]:
+		return fmt.Errorf("Gemini 的输出被拦截（finishReason: %s），请尝试重新生成或更换模型", finishReason)
+	case finishReason == "MAX_TOKENS":
+		return fmt.Errorf("Gemini 在输出内容前达到了长度上限（finishReason: MAX_TOKENS），思考模型可能用完了输出额度，请尝试更换模型")
+	case finishReason != "":
+		return fmt.Errorf("Gemini 未返回内容（finishReason: %s）", finishReason)
+	default:
+		return fmt.Errorf("Gemini 未返回内容，也没有说明结束原因")
+	}
+}
+
+func (p *GeminiProvider) displayName() string {
+	return "Gemini"
+}
+
+func (p *GeminiProvider) modelName() string {
+	return p.model
+}
@@ -0,0 +1,161 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newGeminiTestServer 创建模拟 generateContent 的测试服务器
+func newGeminiTestServer(t *testing.T, response string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-test:generateContent" {
+			t.Errorf("请求路径错误: %s", r.URL.Path)
+		}
+		if got := r.Header.Get("x-goog-api-key"); got != "test-key" {
+			t.Errorf("期望 x-goog-api-key='test-key', 实际='%s'", got)
+		}
+
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("解析请求失败: %v", err)
+		}
+		if req.SystemInstruction == nil || !strings.Contains(req.SystemInstruction.Parts[0].Text, "Types must be one of") {
+			t.Error("系统提示应映射到 systemInstruction")
+		}
+		if len(req.Contents) != 1 || req.Contents[0].Role != roleUser {
+			t.Errorf("用户提示应映射到 contents, 实际=%+v", req.Contents)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+}
+
+func TestGeminiGenerateCommitMessage(t *testing.T) {
+	server := newGeminiTestServer(t, `{
+		"candidates": [{"content": {"role": "model", "parts": [{"text": "docs: update readme"}]}, "finishReason": "STOP"}],
+		"usageMetadata": {"promptTokenCount": 120, "candidatesTokenCount": 8, "totalTokenCount": 128}
+	}`)
+	defer server.Close()
+
+	p, err := newGeminiProvider(ProviderConfig{APIKey: "test-key", BaseURL: server.URL, Model: "gemini-test", Language: "en"})
+	if err != nil {
+		t.Fatalf("创建 Provider 失败: %v", err)
+	}
+
+	msg, err := p.GenerateCommitMessage(context.Background(), &CommitInfo{FilesChanged: []string{"README.md"}})
+	if err != nil {
+		t.Fatalf("生成提交消息失败: %v", err)
+	}
+	if msg.Title != "docs: update readme" {
+		t.Errorf("期望 Title='docs: update readme', 实际='%s'", msg.Title)
+	}
+	if msg.Usage.PromptTokens != 120 || msg.Usage.CompletionTokens != 8 || msg.Usage.TotalTokens != 128 {
+		t.Errorf("token 用量解析错误: %+v", msg.Usage)
+	}
+}
+
+func TestGeminiBlockedResponses(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response string
+		expected string
+	}{
+		{
+			name:     "提示词被拦截",
+			response: `{"promptFeedback": {"blockReason": "SAFETY"}}`,
+			expected: "blockReason: SAFETY",
+		},
+		{
+			name:     "候选被拦截",
+			response: `{"candidates": [{"content": {"parts": []}, "finishReason": "SAFETY"}]}`,
+			expected: "finishReason: SAFETY",
+		},
+		{
+			name:     "无候选",
+			response: `{"candidates": []}`,
+			expected: "未返回任何候选结果",
+		},
+		{
+			name:     "达到长度上限",
+			response: `{"candidates": [{"content": {"parts": []}, "finishReason": "MAX_TOKENS"}]}`,
+			expected: "finishReason: MAX_TOKENS",
+		},
+		{
+			name:     "其他原因",
+			response: `{"candidates": [{"content": {"parts": []}, "finishReason": "OTHER"}]}`,
+			expected: "finishReason: OTHER",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newGeminiTestServer(t, tc.response)
+			defer server.Close()
+
+			p, err := newGeminiProvider(ProviderConfig{APIKey: "test-key", BaseURL: server.URL, Model: "gemini-test", Language: "en"})
+			if err != nil {
+				t.Fatalf("创建 Provider 失败: %v", err)
+			}
+
+			_, err = p.GenerateCommitMessage(context.Background(), &CommitInfo{})
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("期望错误包含 '%s', 实际=%v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestGeminiStreamEmptyResponses(t *testing.T) {
+	testCases := []struct {
+		name     string
+		events   []string
+		expected string
+	}{
+		{
+			name:     "无候选",
+			events:   []string{`{"usageMetadata": {"promptTokenCount": 10, "totalTokenCount": 10}}`},
+			expected: "未返回任何候选结果",
+		},
+		{
+			name:     "达到长度上限",
+			events:   []string{`{"candidates": [{"content": {"parts": []}}]}`, `{"candidates": [{"content": {"parts": []}, "finishReason": "MAX_TOKENS"}]}`},
+			expected: "finishReason: MAX_TOKENS",
+		},
+		{
+			name:     "提示词被拦截",
+			events:   []string{`{"promptFeedback": {"blockReason": "PROHIBITED_CONTENT"}}`},
+			expected: "blockReason: PROHIBITED_CONTENT",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/models/gemini-test:streamGenerateContent" {
+					t.Errorf("请求路径错误: %s", r.URL.Path)
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, event := range tc.events {
+					_, _ = w.Write([]byte("data: " + event + "\n\n"))
+				}
+			}))
+			defer server.Close()
+
+			p, err := newGeminiProvider(ProviderConfig{APIKey: "test-key", BaseURL: server.URL, Model: "gemini-test", Language: "en"})
+			if err != nil {
+				t.Fatalf("创建 Provider 失败: %v", err)
+			}
+
+			_, err = p.GenerateCommitMessageStream(context.Background(), &CommitInfo{}, func(string) {})
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("期望错误包含 '%s', 实际=%v", tc.expected, err)
+			}
+		})
+	}
+}
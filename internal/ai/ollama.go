@@ -0,0 +1,330 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// OllamaDefaultBaseURL 本地 Ollama 服务的默认地址
+	OllamaDefaultBaseURL = "http://localhost:11434"
+	// OllamaDefaultModel Ollama 的默认模型
+	OllamaDefaultModel = "llama3.1"
+)
+
+// OllamaProvider 使用本地 Ollama 服务实现 Provider，diff 不会离开本机
+type OllamaProvider struct {
+	baseURL    string
+	model      string
+	language   string
+	types      []string // 允许的提交类型，为空时使用默认类型
+	httpClient *http.Client
+	retry      RetryPolicy
+	diff       DiffPolicy
+}
+
+// OllamaAPIError Ollama 返回的错误
+type OllamaAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *OllamaAPIError) Error() string {
+	return fmt.Sprintf("请求 Ollama 失败: %d %s", e.StatusCode, e.Message)
+}
+
+// ollamaMessage /api/chat 中的单条消息
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaOptions 模型推理参数
+type ollamaOptions struct {
+	Temperature float32 `json:"temperature,omitempty"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+// ollamaChatRequest /api/chat 请求体
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  ollamaOptions   `json:"options"`
+	// Format 结构化输出的 JSON Schema
+	Format json.RawMessage `json:"format,omitempty"`
+}
+
+// ollamaChatResponse /api/chat 响应体，流式时每行一个
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+// ollamaTagsResponse /api/tags 响应体
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+func init() {
+	// 本地 Ollama 无需 API 密钥，也没有必填字段
+	Register("ollama", Factory{
+		DisplayName:  "Ollama",
+		DefaultModel: OllamaDefaultModel,
+		New: func(cfg ProviderConfig) (Provider, error) {
+			return newOllamaProvider(cfg), nil
+		},
+	})
+}
+
+// newOllamaProvider 创建 Ollama Provider 实例
+func newOllamaProvider(cfg ProviderConfig) *OllamaProvider {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = OllamaDefaultBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = OllamaDefaultModel
+	}
+	model := cfg.Model
+
+	return &OllamaProvider{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		model:    model,
+		language: cfg.Language,
+		types:    cfg.CommitTypes,
+		// 使用默认传输层：遵循 NO_PROXY，访问 localhost 时不会走代理
+		httpClient: newHTTPClient(http.DefaultTransport),
+		retry:      retryPolicyFrom(cfg),
+		// Ollama 默认只保留 num_ctx 个 token 的上下文，预算不能按模型的完整窗口计算
+		diff: diffPolicyWithWindow(cfg, min(ContextWindow(model), ollamaContextWindow)),
+	}
+}
+
+// GenerateCommitMessage 使用本地 Ollama 模型生成提交消息
+func (p *OllamaProvider) GenerateCommitMessage(ctx context.Context, info *CommitInfo) (*CommitMessage, error) {
+	return generateCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, info, p.diff, nil)
+}
+
+// GenerateCommitMessageStream 使用本地 Ollama 模型流式生成提交消息
+func (p *OllamaProvider) GenerateCommitMessageStream(ctx context.Context, info *CommitInfo, onToken func(token string)) (*CommitMessage, error) {
+	return generateCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, info, p.diff, onToken)
+}
+
+// RefineCommitMessage 根据用户反馈修改提交消息
+func (p *OllamaProvider) RefineCommitMessage(ctx context.Context, previous *CommitMessage, feedback string, onToken func(token string)) (*CommitMessage, error) {
+	return refineCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, previous, feedback, onToken)
+}
+
+// GenerateDailyReport 使用本地 Ollama 模型生成日报
+func (p *OllamaProvider) GenerateDailyReport(ctx context.Context, info *ReportInfo, since, until string) (string, error) {
+	return generateDailyReport(ctx, withRetry(p, p.retry), p.language, info, since, until)
+}
+
+// Check 测试 Ollama 服务连通性，并确认配置的模型已经拉取到本地
+func (p *OllamaProvider) Check(ctx context.Context) *CheckResult {
+	result := &CheckResult{
+		ConfigExists:   true,
+		APIKeyOptional: true,
+		Provider:       "ollama",
+		Model:          p.model,
+		BaseURL:        p.baseURL,
+	}
+
+	start := time.Now()
+	models, err := p.ListModels(ctx)
+	result.ResponseTime = time.Since(start)
+	if err != nil {
+		result.Error = fmt.Errorf("API 连接失败: %w", err)
+		return result
+	}
+
+	result.APIConnected = true
+	result.Models = models
+	if !hasOllamaModel(models, p.model) {
+		result.Error = fmt.Errorf("本地未找到模型 %s，请先运行 'ollama pull %s'", p.model, p.model)
+	}
+
+	return result
+}
+
+// ListModels 通过 /api/tags 列出本地已拉取的模型
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Ollama 请求失败: %w", err)
+	}
+
+	respData, err := p.do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags ollamaTagsResponse
+	if err := json.Unmarshal(respData, &tags); err != nil {
+		return nil, fmt.Errorf("解析 Ollama 模型列表失败: %w", err)
+	}
+
+	models := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		models = append(models, m.Name)
+	}
+	return models, nil
+}
+
+// hasOllamaModel 判断模型是否已存在，未写标签的名称按 ":latest" 匹配
+func hasOllamaModel(models []string, model string) bool {
+	for _, m := range models {
+		if m == model || m == model+":latest" {
+			return true
+		}
+	}
+	return false
+}
+
+// chat 将通用请求转换为 Ollama /api/chat 请求
+func (p *OllamaProvider) chat(ctx context.Context, req *chatRequest) (*chatResponse, error) {
+	body := ollamaChatRequest{
+		Model:  p.model,
+		Stream: req.OnToken != nil,
+		Options: ollamaOptions{
+			Temperature: req.Temperature,
+			NumPredict:  req.MaxTokens,
+		},
+	}
+	if req.Schema != nil {
+		body.Format = marshalSchema(req.Schema)
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, ollamaMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		body.Messages = append(body.Messages, ollamaMessage{Role: m.Role, Content: m.Content})
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("序列化 Ollama 请求失败: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("创建 Ollama 请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	if req.OnToken != nil {
+		return p.chatStream(httpReq, req.OnToken)
+	}
+
+	respData, err := p.do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var result ollamaChatResponse
+	if err := json.Unmarshal(respData, &result); err != nil {
+		return nil, fmt.Errorf("解析 Ollama 响应失败: %w", err)
+	}
+	return &chatResponse{
+		Content: result.Message.Content,
+		Usage: Usage{
+			PromptTokens:     result.PromptEvalCount,
+			CompletionTokens: result.EvalCount,
+			TotalTokens:      result.PromptEvalCount + result.EvalCount,
+		},
+	}, nil
+}
+
+// chatStream 读取 /api/chat 的流式响应，每行是一个 JSON 对象
+func (p *OllamaProvider) chatStream(httpReq *http.Request, onToken func(string)) (*chatResponse, error) {
+	resp, err := p.send(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &chatResponse{}
+	var content strings.Builder
+	err = readJSONLines(resp.Body, func(line []byte) error {
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("解析 Ollama 流式响应失败: %w", err)
+		}
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			onToken(chunk.Message.Content)
+		}
+		if chunk.Done {
+			result.Usage = Usage{
+				PromptTokens:     chunk.PromptEvalCount,
+				CompletionTokens: chunk.EvalCount,
+				TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Content = content.String()
+	return result, nil
+}
+
+// do 发送请求并返回响应体
+func (p *OllamaProvider) do(req *http.Request) ([]byte, error) {
+	resp, err := p.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取 Ollama 响应失败: %w", err)
+	}
+	return data, nil
+}
+
+// send 发送请求，非 200 状态码视为错误
+func (p *OllamaProvider) send(req *http.Request) (*http.Response, error) {
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Ollama 失败（请确认 'ollama serve' 已启动）: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error != "" {
+			message = errResp.Error
+		}
+		return nil, &OllamaAPIError{StatusCode: resp.StatusCode, Message: message}
+	}
+
+	return resp, nil
+}
+
+func (p *OllamaProvider) displayName() string {
+	return "Ollama"
+}
+
+func (p *OllamaProvider) modelName() string {
+	return p.model
+}
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newOllamaTestServer 创建模拟 Ollama 的测试服务器
+func newOllamaTestServer(t *testing.T, models []string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			var resp ollamaTagsResponse
+			for _, m := range models {
+				resp.Models = append(resp.Models, struct {
+					Name string `json:"name"`
+				}{Name: m})
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		case "/api/chat":
+			var req ollamaChatRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("解析请求失败: %v", err)
+			}
+			if req.Stream {
+				t.Error("期望非流式请求")
+			}
+			if len(req.Messages) != 2 || req.Messages[0].Role != "system" {
+				t.Errorf("期望 system + user 两条消息, 实际=%+v", req.Messages)
+			}
+			if !strings.Contains(req.Messages[0].Content, "Types must be one of") {
+				t.Error("应复用统一的系统提示")
+			}
+			_ = json.NewEncoder(w).Encode(ollamaChatResponse{
+				Message: ollamaMessage{Role: roleAssistant, Content: "fix(db): close rows\n\nRelease connections early"},
+				Done:    true,
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestOllamaGenerateCommitMessage(t *testing.T) {
+	server := newOllamaTestServer(t, nil)
+	defer server.Close()
+
+	p := newOllamaProvider(ProviderConfig{BaseURL: server.URL, Language: "en"})
+	msg, err := p.GenerateCommitMessage(context.Background(), &CommitInfo{
+		FilesChanged: []string{"db.go"},
+		DiffContent:  "+defer rows.Close()",
+		BranchName:   "main",
+	})
+	if err != nil {
+		t.Fatalf("生成提交消息失败: %v", err)
+	}
+	if msg.Title != "fix(db): close rows" {
+		t.Errorf("期望 Title='fix(db): close rows', 实际='%s'", msg.Title)
+	}
+}
+
+func TestOllamaCheck(t *testing.T) {
+	server := newOllamaTestServer(t, []string{"llama3.1:latest", "qwen2.5:7b"})
+	defer server.Close()
+
+	result := newOllamaProvider(ProviderConfig{BaseURL: server.URL, Model: "llama3.1", Language: "en"}).Check(context.Background())
+	if !result.APIConnected || result.Error != nil {
+		t.Fatalf("期望检测通过, 实际错误=%v", result.Error)
+	}
+	if len(result.Models) != 2 {
+		t.Errorf("期望列出 2 个模型, 实际=%v", result.Models)
+	}
+
+	result = newOllamaProvider(ProviderConfig{BaseURL: server.URL, Model: "mistral", Language: "en"}).Check(context.Background())
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "ollama pull mistral") {
+		t.Errorf("模型缺失时应提示拉取, 实际=%v", result.Error)
+	}
+}
+
+func TestOllamaGenerateCommitMessageStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("解析请求失败: %v", err)
+		}
+		if !req.Stream {
+			t.Error("期望流式请求")
+		}
+		_, _ = w.Write([]byte(`{"message":{"role":"assistant","content":"chore: "},"done":false}
+{"message":{"role":"assistant","content":"bump deps"},"done":false}
+{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":30,"eval_count":5}
+`))
+	}))
+	defer server.Close()
+
+	var streamed strings.Builder
+	msg, err := newOllamaProvider(ProviderConfig{BaseURL: server.URL, Language: "en"}).GenerateCommitMessageStream(
+		context.Background(), &CommitInfo{}, func(token string) { streamed.WriteString(token) })
+	if err != nil {
+		t.Fatalf("流式生成失败: %v", err)
+	}
+	if streamed.String() != "chore: bump deps" || msg.Title != "chore: bump deps" {
+		t.Errorf("流式输出与最终结果不一致: streamed='%s', title='%s'", streamed.String(), msg.Title)
+	}
+	if msg.Usage.TotalTokens != 35 {
+		t.Errorf("期望 TotalTokens=35, 实际=%d", msg.Usage.TotalTokens)
+	}
+}
@@ -0,0 +1,447 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SimonGino/aicommit/internal/lint"
+)
+
+// CommitType 定义提交类型
+type CommitType struct {
+	Type        string
+	Description string
+}
+
+// 提交类型定义
+var commitTypes = map[string][]CommitType{
+	"en": {
+		{"feat", "New feature"},
+		{"fix", "Bug fix"},
+		{"refactor", "Code refactoring"},
+		{"docs", "Documentation changes"},
+		{"style", "Code style changes (formatting, missing semicolons, etc)"},
+		{"test", "Adding or modifying tests"},
+		{"chore", "Maintenance tasks, dependencies, build changes"},
+	},
+	"zh-CN": {
+		{"feat", "新功能"},
+		{"fix", "修复缺陷"},
+		{"refactor", "代码重构"},
+		{"docs", "文档更新"},
+		{"style", "代码格式"},
+		{"test", "测试相关"},
+		{"chore", "其他更新"},
+	},
+	"zh-TW": {
+		{"feat", "新功能"},
+		{"fix", "修復缺陷"},
+		{"refactor", "代碼重構"},
+		{"docs", "文檔更新"},
+		{"style", "代碼格式"},
+		{"test", "測試相關"},
+		{"chore", "其他更新"},
+	},
+}
+
+// buildFilesList 构建文件列表字符串
+func buildFilesList(files []string) string {
+	var filesList strings.Builder
+	for _, file := range files {
+		filesList.WriteString("- ")
+		filesList.WriteString(file)
+		filesList.WriteString("\n")
+	}
+	return filesList.String()
+}
+
+// cleanMarkdownFormatting 清理Markdown格式标记
+func cleanMarkdownFormatting(content string) string {
+	// 移除 ```plaintext 和 ``` 标记
+	content = strings.TrimPrefix(content, "```plaintext")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	// 移除开头的空行
+	content = strings.TrimLeft(content, "\n")
+
+	// 移除随机添加的issue引用（如果不是用户明确要求的）
+	// 匹配中文的"修复 #数字"或英文的"Fixes #数字"等格式
+	lines := strings.Split(content, "\n")
+	filteredLines := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		if isIssueReference(line) {
+			continue
+		}
+		filteredLines = append(filteredLines, line)
+	}
+
+	return strings.Join(filteredLines, "\n")
+}
+
+// isIssueReference 判断是否为模型随意添加的"修复 #数字"、"Fixes #数字"等 issue 引用
+func isIssueReference(line string) bool {
+	trimmedLine := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmedLine, "修复 #") ||
+		strings.HasPrefix(trimmedLine, "Fixes #") ||
+		strings.HasPrefix(trimmedLine, "fixes #") ||
+		strings.HasPrefix(trimmedLine, "Fix #") ||
+		strings.HasPrefix(trimmedLine, "fix #") ||
+		strings.HasPrefix(trimmedLine, "Closes #") ||
+		strings.HasPrefix(trimmedLine, "closes #")
+}
+
+// userPrompt 根据语言返回用户提示
+func userPrompt(language string, info *CommitInfo, filesList string) string {
+	switch language {
+	case "zh-CN":
+		return fmt.Sprintf(`请为以下Git更改生成标准化的提交信息：
+
+分支：%s
+
+更改的文件：
+%s
+更改内容：
+%s
+
+请严格按照系统提示中的格式要求生成提交信息。`,
+			info.BranchName,
+			filesList,
+			info.DiffContent)
+	case "zh-TW":
+		return fmt.Sprintf(`請為以下Git更改生成標準化的提交信息：
+
+分支：%s
+
+更改的文件：
+%s
+更改內容：
+%s
+
+請嚴格按照系統提示中的格式要求生成提交信息。`,
+			info.BranchName,
+			filesList,
+			info.DiffContent)
+	default:
+		return fmt.Sprintf(`Please generate a standardized commit message for the following Git changes:
+
+Branch: %s
+
+Files changed:
+%s
+Changes:
+%s
+
+Please strictly follow the format requirements in the system prompt.`,
+			info.BranchName,
+			filesList,
+			info.DiffContent)
+	}
+}
+
+// reportPrompt 根据语言返回生成日报的用户提示
+func reportPrompt(language string, info *ReportInfo, since, until string) string {
+	// 将提交列表格式化为 "- YYYY-MM-DD -- Subject"
+	var commitsFormatted strings.Builder
+	for _, commit := range info.Commits {
+		commitsFormatted.WriteString("- ")
+		commitsFormatted.WriteString(commit)
+		commitsFormatted.WriteString("\n")
+	}
+	commitsList := strings.TrimSpace(commitsFormatted.String())
+
+	switch language {
+	case "zh-CN":
+		return fmt.Sprintf(`请根据以下 Git commit 记录（格式为 "- YYYY-MM-DD -- Commit Subject"），为日期范围 %s 至 %s 总结生成一份简洁的工作日报。
+
+要求：
+1.  使用 Markdown 格式。
+2.  按日期**总结**当天完成的主要工作，**不要**罗列单个 commit message。
+3.  忽略所有 "Merge branch" 或 "Merge remote-tracking branch" 相关的提交。
+4.  报告标题或开头应明确指出报告的时间范围是 %s 到 %s。
+5.  语言为简体中文。
+
+Commit 记录:
+%s
+
+请生成日报内容：`, since, until, since, until, commitsList)
+	case "zh-TW":
+		return fmt.Sprintf(`請根據以下 Git commit 記錄（格式為 "- YYYY-MM-DD -- Commit Subject"），為日期範圍 %s 至 %s 總結生成一份簡潔的工作日報。
+
+要求：
+1.  使用 Markdown 格式。
+2.  按日期**總結**當天完成的主要工作，**不要**羅列單個 commit message。
+3.  忽略所有 "Merge branch" 或 "Merge remote-tracking branch" 相關的提交。
+4.  報告標題或開頭應明確指出報告的時間範圍是 %s 到 %s。
+5.  語言為繁體中文。
+
+Commit 記錄:
+%s
+
+請生成日報內容：`, since, until, since, until, commitsList)
+	default:
+		return fmt.Sprintf(`Please summarize the following Git commit records (formatted as "- YYYY-MM-DD -- Commit Subject") into a concise work report for the period %s to %s.
+
+Requirements:
+1.  Use Markdown format.
+2.  Summarize the main work completed **per day**. **Do not** list individual commit messages.
+3.  Ignore any commits related to "Merge branch" or "Merge remote-tracking branch".
+4.  The report title or beginning should clearly state the reporting period is from %s to %s.
+5.  The language should be English.
+
+Commit Records:
+%s
+
+Please generate the report content:`, since, until, since, until, commitsList)
+	}
+}
+
+// CommitTypeNames 返回默认的提交类型名称，未配置提交类型时系统提示、JSON schema 和 lint 检查都使用这些类型
+func CommitTypeNames() []string {
+	var names []string
+	for _, t := range commitTypes["en"] {
+		names = append(names, t.Type)
+	}
+	return names
+}
+
+// commitTypesFor 返回指定语言的提交类型
+func commitTypesFor(language string) []CommitType {
+	types, ok := commitTypes[language]
+	if !ok {
+		return commitTypes["en"]
+	}
+	return types
+}
+
+// commitStyle 生成提交信息时使用的语言和提交类型
+type commitStyle struct {
+	language string
+	// types 允许的提交类型，为空时使用默认类型
+	types []string
+}
+
+// commitTypes 返回允许的提交类型，默认类型使用对应语言的说明，自定义类型没有说明
+func (s commitStyle) commitTypes() []CommitType {
+	defaults := commitTypesFor(s.language)
+	if len(s.types) == 0 {
+		return defaults
+	}
+
+	types := make([]CommitType, 0, len(s.types))
+	for _, name := range s.types {
+		t := CommitType{Type: name}
+		if i := slices.IndexFunc(defaults, func(d CommitType) bool { return d.Type == name }); i >= 0 {
+			t.Description = defaults[i].Description
+		}
+		types = append(types, t)
+	}
+	return types
+}
+
+// typeNames 返回允许的提交类型名称，与 lint 检查使用的类型一致
+func (s commitStyle) typeNames() []string {
+	if len(s.types) == 0 {
+		return CommitTypeNames()
+	}
+	return s.types
+}
+
+// systemPrompt 返回系统提示：提交信息格式规则和 JSON 输出要求
+func systemPrompt(style commitStyle) string {
+	return formatRulesPrompt(style) + "\n\n" + jsonOutputPrompt(style.language)
+}
+
+// jsonOutputPrompt 要求模型以 JSON 返回提交信息的各个部分
+// 不支持 response_format 或工具调用的模型依赖该说明输出 JSON
+func jsonOutputPrompt(language string) string {
+	switch language {
+	case "zh-CN":
+		return `请只输出一个 JSON 对象，不要添加任何其他文字或 Markdown 代码块，字段如下：
+{"type": "<类型>", "scope": "<范围，没有时为空字符串>", "subject": "<主题>", "body": "<正文，可为空字符串>", "breaking": <是否包含重大变更，true 或 false>, "footers": ["<脚注，如 BREAKING CHANGE: 说明，没有时为空数组>"]}`
+	case "zh-TW":
+		return `請只輸出一個 JSON 對象，不要添加任何其他文字或 Markdown 代碼塊，字段如下：
+{"type": "<類型>", "scope": "<範圍，沒有時為空字符串>", "subject": "<主題>", "body": "<正文，可為空字符串>", "breaking": <是否包含重大變更，true 或 false>, "footers": ["<腳註，如 BREAKING CHANGE: 說明，沒有時為空數組>"]}`
+	default:
+		return `Respond with a single JSON object only, without any other text or Markdown code fences, using these fields:
+{"type": "<type>", "scope": "<scope, empty string if none>", "subject": "<subject>", "body": "<body, may be empty>", "breaking": <true or false>, "footers": ["<footer, e.g. BREAKING CHANGE: details; empty array if none>"]}`
+	}
+}
+
+// formatRulesPrompt 根据语言返回提交信息的格式规则
+func formatRulesPrompt(style commitStyle) string {
+	// 构建类型说明
+	var typeDesc string
+	for _, t := range style.commitTypes() {
+		if t.Description == "" {
+			typeDesc += fmt.Sprintf("- %s\n", t.Type)
+			continue
+		}
+		typeDesc += fmt.Sprintf("- %s: %s\n", t.Type, t.Description)
+	}
+
+	switch style.language {
+	case "zh-CN":
+		return fmt.Sprintf(`您是一个帮助生成标准化git提交信息的助手。
+请严格遵循以下提交信息格式规则：
+
+1. 格式：<类型>(<范围>): <主题>
+
+<正文>
+
+<脚注>
+
+2. 类型必须是以下之一：
+%s
+3. 范围：可选，描述影响的区域（如：router、auth、db）
+4. 主题：简短摘要（不超过50个字符）
+5. 正文：详细说明（每行不超过72个字符）
+6. 脚注：可选，用于说明重大变更或引用问题编号
+
+示例：
+feat(认证): 实现JWT认证系统
+
+添加基于JWT的认证系统，支持刷新令牌
+- 实现令牌生成和验证
+- 添加用户会话管理
+- 设置安全Cookie处理
+
+BREAKING CHANGE: 需要新的认证头
+修复 #123`, typeDesc)
+
+	case "zh-TW":
+		return fmt.Sprintf(`您是一個幫助生成標準化git提交信息的助手。
+請嚴格遵循以下提交信息格式規則：
+
+1. 格式：<類型>(<範圍>): <主題>
+
+<正文>
+
+<腳註>
+
+2. 類型必須是以下之一：
+%s
+3. 範圍：可選，描述影響的區域（如：router、auth、db）
+4. 主題：簡短摘要（不超過50個字符）
+5. 正文：詳細說明（每行不超過72個字符）
+6. 腳註：可選，用於說明重大變更或引用問題編號
+
+示例：
+feat(認證): 實現JWT認證系統
+
+添加基於JWT的認證系統，支持刷新令牌
+- 實現令牌生成和驗證
+- 添加用戶會話管理
+- 設置安全Cookie處理
+
+BREAKING CHANGE: 需要新的認證頭
+修復 #123`, typeDesc)
+
+	default:
+		return fmt.Sprintf(`You are a helpful assistant that generates standardized git commit messages.
+Follow these strict rules for commit message format:
+
+1. Format: <type>(<scope>): <subject>
+
+<body>
+
+<footer>
+
+2. Types must be one of:
+%s
+3. Scope: Optional, describes the affected area (e.g., router, auth, db)
+4. Subject: Short summary (50 chars or less)
+5. Body: Detailed explanation (72 chars per line)
+6. Footer: Optional, for breaking changes or issue references
+
+Example:
+feat(auth): implement JWT authentication
+
+Add JWT-based authentication system with refresh tokens
+- Implement token generation and validation
+- Add user session management
+- Set up secure cookie handling
+
+BREAKING CHANGE: New authentication headers required
+Fixes #123`, typeDesc)
+	}
+}
+
+// summarySystemPrompt 分块总结 diff 时使用的系统提示
+func summarySystemPrompt(language string) string {
+	switch language {
+	case "zh-CN":
+		return `您会收到一次提交中部分文件的 git diff。请用不超过 5 条要点简要总结这些更改做了什么以及原因，只输出要点，不要生成提交信息。`
+	case "zh-TW":
+		return `您會收到一次提交中部分文件的 git diff。請用不超過 5 條要點簡要總結這些更改做了什麼以及原因，只輸出要點，不要生成提交信息。`
+	default:
+		return `You will receive the git diff of some files in a commit. Summarize what the changes do and why in at most 5 short bullet points. Output only the bullet points, not a commit message.`
+	}
+}
+
+// summariesHeader 分块总结后替代 diff 发送的说明
+func summariesHeader(language string) string {
+	switch language {
+	case "zh-CN":
+		return "diff 过大，以下是按文件分组的更改摘要：\n"
+	case "zh-TW":
+		return "diff 過大，以下是按文件分組的更改摘要：\n"
+	default:
+		return "The diff is too large; below are summaries of the changes grouped by file:\n"
+	}
+}
+
+// lintFeedbackPrompt 提交信息违反规范时要求模型修正的用户消息
+func lintFeedbackPrompt(language string, issues []lint.Issue) string {
+	var list strings.Builder
+	for _, issue := range issues {
+		if issue.Severity == lint.SeverityError {
+			fmt.Fprintf(&list, "- %s\n", issue.Message)
+		}
+	}
+
+	switch language {
+	case "zh-CN":
+		return "上面的提交信息不符合规范：\n" + list.String() + "请修正这些问题，保持相同的格式，只输出修改后的提交信息。"
+	case "zh-TW":
+		return "上面的提交信息不符合規範：\n" + list.String() + "請修正這些問題，保持相同的格式，只輸出修改後的提交信息。"
+	default:
+		return "The commit message above violates these rules:\n" + list.String() + "Fix them, keep the same format and output only the revised commit message."
+	}
+}
+
+// refinePrompt 根据用户反馈修改提交消息时追加的用户消息
+func refinePrompt(language, feedback string) string {
+	switch language {
+	case "zh-CN":
+		return fmt.Sprintf("请根据以下反馈修改上面的提交信息，保持相同的格式，只输出修改后的提交信息：\n%s", feedback)
+	case "zh-TW":
+		return fmt.Sprintf("請根據以下反饋修改上面的提交信息，保持相同的格式，只輸出修改後的提交信息：\n%s", feedback)
+	default:
+		return fmt.Sprintf("Revise the commit message above according to this feedback. Keep the same format and output only the revised commit message:\n%s", feedback)
+	}
+}
+
+// parseCommitMessage 解析模型返回的提交信息
+// 优先按结构化 JSON 解析，不支持结构化输出的模型返回纯文本时拆分为标题和正文
+func parseCommitMessage(content string) (*CommitMessage, error) {
+	if message, ok, err := parseStructuredMessage(content); ok {
+		return message, err
+	}
+
+	content = cleanMarkdownFormatting(content)
+
+	// 分割标题和正文
+	parts := strings.SplitN(content, "\n\n", 2)
+	message := &CommitMessage{
+		Title: strings.TrimSpace(parts[0]),
+	}
+	if len(parts) > 1 {
+		message.Body = strings.TrimSpace(parts[1])
+	}
+
+	message.parseHeader()
+	return message, nil
+}
@@ -2,7 +2,9 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -25,62 +27,73 @@ func (t *azureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 // CommitInfo 包含生成提交消息所需的信息
 type CommitInfo struct {
+	// FilesChanged 暂存的文件，内容未包含在 DiffContent 中的文件带有 ContentOmitted 标记
 	FilesChanged []string
 	DiffContent  string
 	BranchName   string
 }
 
+// ContentOmitted 标记内容被 .aicommitignore 排除、只发送文件名的文件
+const ContentOmitted = "(content omitted)"
+
 // CommitMessage 表示生成的提交消息
 type CommitMessage struct {
+	// Title 完整的标题行，格式为 "<type>(<scope>)!: <subject>"
 	Title string
-	Body  string
+	// Body 正文，包含脚注
+	Body string
+
+	// Type 提交类型（如 feat、fix），标题不符合约定格式时为空
+	Type string
+	// Scope 影响范围，可为空
+	Scope string
+	// Subject 标题中的简短描述
+	Subject string
+	// Breaking 是否包含破坏性变更
+	Breaking bool
+	// Footers 脚注行（如 "Fixes #123"、"BREAKING CHANGE: ..."）
+	Footers []string
+
+	Usage Usage
+	// Provider 生成该消息的提供商名称，由 FallbackProvider 填写
+	Provider string
+	// Model 生成该消息使用的模型
+	Model string
+	// Warnings 生成过程中需要提示用户的情况，如 diff 块数过多改为截断
+	Warnings []string
+
+	// conversation 生成该消息的对话（不含系统提示），最后一条为模型的回复，用于根据反馈修改
+	conversation []chatMessage
 }
 
-// ReportInfo 包含生成日报所需的信息
-type ReportInfo struct {
-	Commits []string
+// Text 返回完整的提交信息：标题、空行和正文
+func (m *CommitMessage) Text() string {
+	if m.Body == "" {
+		return m.Title
+	}
+	return m.Title + "\n\n" + m.Body
 }
 
-// CommitType 定义提交类型
-type CommitType struct {
-	Type        string
-	Description string
+// Usage 记录一次请求的 token 用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
-// 提交类型定义
-var commitTypes = map[string][]CommitType{
-	"en": {
-		{"feat", "New feature"},
-		{"fix", "Bug fix"},
-		{"refactor", "Code refactoring"},
-		{"docs", "Documentation changes"},
-		{"style", "Code style changes (formatting, missing semicolons, etc)"},
-		{"test", "Adding or modifying tests"},
-		{"chore", "Maintenance tasks, dependencies, build changes"},
-	},
-	"zh-CN": {
-		{"feat", "新功能"},
-		{"fix", "修复缺陷"},
-		{"refactor", "代码重构"},
-		{"docs", "文档更新"},
-		{"style", "代码格式"},
-		{"test", "测试相关"},
-		{"chore", "其他更新"},
-	},
-	"zh-TW": {
-		{"feat", "新功能"},
-		{"fix", "修復缺陷"},
-		{"refactor", "代碼重構"},
-		{"docs", "文檔更新"},
-		{"style", "代碼格式"},
-		{"test", "測試相關"},
-		{"chore", "其他更新"},
-	},
+// ReportInfo 包含生成日报所需的信息
+type ReportInfo struct {
+	Commits []string
 }
 
 // Provider 定义了AI提供商的接口
 type Provider interface {
 	GenerateCommitMessage(ctx context.Context, info *CommitInfo) (*CommitMessage, error)
+	// GenerateCommitMessageStream 流式生成提交消息，每收到一段文本调用一次 onToken
+	GenerateCommitMessageStream(ctx context.Context, info *CommitInfo, onToken func(token string)) (*CommitMessage, error)
+	// RefineCommitMessage 在生成 previous 的对话基础上追加用户反馈，生成修改后的提交消息
+	// onToken 非空时以流式方式生成
+	RefineCommitMessage(ctx context.Context, previous *CommitMessage, feedback string, onToken func(token string)) (*CommitMessage, error)
 	GenerateDailyReport(ctx context.Context, info *ReportInfo, since, until string) (string, error)
 }
 
@@ -90,97 +103,125 @@ type OpenAIProvider struct {
 	baseURL  string
 	model    string
 	language string
+	types    []string // 允许的提交类型，为空时使用默认类型
 	provider string
 	client   *openai.Client
+	retry    RetryPolicy
+	diff     DiffPolicy
 }
 
-// NewProvider 创建统一的 Provider 实例，支持 OpenAI 和 Azure OpenAI
-func NewProvider(apiKey, baseURL, model, language, provider, azureAPIVersion string) (Provider, error) {
+func init() {
+	Register("openai", Factory{
+		DisplayName:  "OpenAI",
+		Required:     []ConfigField{FieldAPIKey},
+		DefaultModel: openai.GPT4o,
+		APIKeyEnv:    "OPENAI_API_KEY",
+		New: func(cfg ProviderConfig) (Provider, error) {
+			return newOpenAIProvider("openai", cfg)
+		},
+	})
+	Register("azure", Factory{
+		DisplayName: "Azure OpenAI",
+		Required:    []ConfigField{FieldAPIKey, FieldBaseURL, FieldModel},
+		// Azure OpenAI 通常使用部署名称作为模型名
+		DefaultModel: "gpt-4o",
+		APIKeyEnv:    "AZURE_OPENAI_API_KEY",
+		New: func(cfg ProviderConfig) (Provider, error) {
+			return newOpenAIProvider("azure", cfg)
+		},
+	})
+}
+
+// newOpenAIProvider 创建基于 go-openai 的 Provider 实例，支持 OpenAI 和 Azure OpenAI
+func newOpenAIProvider(provider string, cfg ProviderConfig) (*OpenAIProvider, error) {
 	var config openai.ClientConfig
 	var effectiveBaseURL string
 
 	// 根据 provider 类型决定使用哪个配置
 	switch provider {
 	case "azure":
-		if apiKey == "" {
+		if cfg.APIKey == "" {
 			return nil, fmt.Errorf("Azure OpenAI API 密钥不能为空")
 		}
-		if baseURL == "" {
+		if cfg.BaseURL == "" {
 			return nil, fmt.Errorf("Azure OpenAI endpoint URL 不能为空")
 		}
-		if model == "" {
+		if cfg.Model == "" {
 			return nil, fmt.Errorf("Azure OpenAI 模型/部署名称不能为空")
 		}
 
 		// 如果没有指定 API 版本，使用默认版本
+		azureAPIVersion := cfg.AzureAPIVersion
 		if azureAPIVersion == "" {
 			azureAPIVersion = "2024-02-15-preview"
 		}
 
 		// Azure OpenAI 配置
-		azureBaseURL := strings.TrimRight(baseURL, "/")
-		config = openai.DefaultAzureConfig(apiKey, azureBaseURL)
+		azureBaseURL := strings.TrimRight(cfg.BaseURL, "/")
+		config = openai.DefaultAzureConfig(cfg.APIKey, azureBaseURL)
 		config.APIVersion = azureAPIVersion
 
 		// 创建自定义HTTP客户端以添加正确的认证头
-		config.HTTPClient = &http.Client{
-			Transport: &azureTransport{
-				transport: http.DefaultTransport,
-				apiKey:    apiKey,
-			},
-		}
+		config.HTTPClient = newHTTPClient(&azureTransport{
+			transport: proxyTransport(),
+			apiKey:    cfg.APIKey,
+		})
 
 		effectiveBaseURL = azureBaseURL
 
 	default:
 		// 默认使用 OpenAI
-		if apiKey == "" {
+		if cfg.APIKey == "" {
 			return nil, fmt.Errorf("OpenAI API 密钥不能为空")
 		}
-		config = openai.DefaultConfig(apiKey)
-		effectiveBaseURL = baseURL
+		config = openai.DefaultConfig(cfg.APIKey)
+		effectiveBaseURL = cfg.BaseURL
 
 		// 设置自定义 URL (仅对 OpenAI)
-		if baseURL != "" {
-			config.BaseURL = baseURL
-		}
-	}
-
-	// 检查是否需要设置代理
-	if proxyURL := getEnv("HTTP_PROXY", ""); proxyURL != "" {
-		proxy, err := url.Parse(proxyURL)
-		if err == nil {
-			transport := &http.Transport{
-				Proxy: http.ProxyURL(proxy),
-			}
-			config.HTTPClient = &http.Client{
-				Transport: transport,
-			}
+		if cfg.BaseURL != "" {
+			config.BaseURL = cfg.BaseURL
 		}
+		config.HTTPClient = newHTTPClient(proxyTransport())
 	}
 
 	// 如果没有指定模型，使用默认模型
+	model := cfg.Model
 	if model == "" {
-		if provider == "azure" {
-			// Azure OpenAI 通常使用部署名称作为模型名
-			model = "gpt-4o"
-		} else {
-			model = openai.GPT4o
-		}
+		model = openai.GPT4o
 	}
 
 	providerInstance := &OpenAIProvider{
-		apiKey:   apiKey,
+		apiKey:   cfg.APIKey,
 		baseURL:  effectiveBaseURL,
 		model:    model,
-		language: language,
+		language: cfg.Language,
+		types:    cfg.CommitTypes,
 		provider: provider,
 		client:   openai.NewClientWithConfig(config),
+		retry:    retryPolicyFrom(cfg),
+		diff:     diffPolicyFrom(cfg),
 	}
 
 	return providerInstance, nil
 }
 
+// newHTTPClient 创建提供商使用的 HTTP 客户端，失败响应的 Retry-After 会传递给重试策略
+func newHTTPClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: &retryHintTransport{transport: transport},
+	}
+}
+
+// proxyTransport 返回 HTTP 传输层，设置了 HTTP_PROXY 时通过代理发送请求
+func proxyTransport() http.RoundTripper {
+	if proxyURL := getEnv("HTTP_PROXY", ""); proxyURL != "" {
+		if proxy, err := url.Parse(proxyURL); err == nil {
+			return &http.Transport{Proxy: http.ProxyURL(proxy)}
+		}
+	}
+	return http.DefaultTransport
+}
+
 // 获取环境变量，如果不存在则返回默认值
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -191,498 +232,197 @@ func getEnv(key, defaultValue string) string {
 
 // BuildFilesList 构建文件列表字符串
 func (p *OpenAIProvider) BuildFilesList(files []string) string {
-	var filesList strings.Builder
-	for _, file := range files {
-		filesList.WriteString("- ")
-		filesList.WriteString(file)
-		filesList.WriteString("\n")
-	}
-	return filesList.String()
+	return buildFilesList(files)
 }
 
 // CleanMarkdownFormatting 清理Markdown格式标记
 func (p *OpenAIProvider) CleanMarkdownFormatting(content string) string {
-	// 移除 ```plaintext 和 ``` 标记
-	content = strings.TrimPrefix(content, "```plaintext")
-	content = strings.TrimPrefix(content, "```")
-	content = strings.TrimSuffix(content, "```")
-
-	// 移除开头的空行
-	content = strings.TrimLeft(content, "\n")
-
-	// 移除随机添加的issue引用（如果不是用户明确要求的）
-	// 匹配中文的"修复 #数字"或英文的"Fixes #数字"等格式
-	lines := strings.Split(content, "\n")
-	filteredLines := make([]string, 0, len(lines))
-
-	for _, line := range lines {
-		trimmedLine := strings.TrimSpace(line)
-		// 跳过匹配"修复 #数字"、"Fixes #数字"等格式的行
-		if strings.HasPrefix(trimmedLine, "修复 #") ||
-			strings.HasPrefix(trimmedLine, "Fixes #") ||
-			strings.HasPrefix(trimmedLine, "fixes #") ||
-			strings.HasPrefix(trimmedLine, "Fix #") ||
-			strings.HasPrefix(trimmedLine, "fix #") ||
-			strings.HasPrefix(trimmedLine, "Closes #") ||
-			strings.HasPrefix(trimmedLine, "closes #") {
-			continue
-		}
-		filteredLines = append(filteredLines, line)
-	}
-
-	return strings.Join(filteredLines, "\n")
+	return cleanMarkdownFormatting(content)
 }
 
 // GetUserPrompt 根据语言返回用户提示
 func (p *OpenAIProvider) GetUserPrompt(info *CommitInfo, filesList string) string {
-	switch p.language {
-	case "zh-CN":
-		return fmt.Sprintf(`请为以下Git更改生成标准化的提交信息：
-
-分支：%s
-
-更改的文件：
-%s
-更改内容：
-%s
-
-请严格按照系统提示中的格式要求生成提交信息。`,
-			info.BranchName,
-			filesList,
-			info.DiffContent)
-	case "zh-TW":
-		return fmt.Sprintf(`請為以下Git更改生成標準化的提交信息：
-
-分支：%s
-
-更改的文件：
-%s
-更改內容：
-%s
-
-請嚴格按照系統提示中的格式要求生成提交信息。`,
-			info.BranchName,
-			filesList,
-			info.DiffContent)
-	default:
-		return fmt.Sprintf(`Please generate a standardized commit message for the following Git changes:
-
-Branch: %s
-
-Files changed:
-%s
-Changes:
-%s
-
-Please strictly follow the format requirements in the system prompt.`,
-			info.BranchName,
-			filesList,
-			info.DiffContent)
-	}
+	return userPrompt(p.language, info, filesList)
 }
 
 // GetUserPromptForReport 根据语言返回生成日报的用户提示
 func (p *OpenAIProvider) GetUserPromptForReport(info *ReportInfo, since, until string) string {
-	// 将提交列表格式化为 "- YYYY-MM-DD -- Subject"
-	var commitsFormatted strings.Builder
-	for _, commit := range info.Commits {
-		commitsFormatted.WriteString("- ")
-		commitsFormatted.WriteString(commit)
-		commitsFormatted.WriteString("\n")
-	}
-	commitsList := strings.TrimSpace(commitsFormatted.String())
-
-	switch p.language {
-	case "zh-CN":
-		return fmt.Sprintf(`请根据以下 Git commit 记录（格式为 "- YYYY-MM-DD -- Commit Subject"），为日期范围 %s 至 %s 总结生成一份简洁的工作日报。
-
-要求：
-1.  使用 Markdown 格式。
-2.  按日期**总结**当天完成的主要工作，**不要**罗列单个 commit message。
-3.  忽略所有 "Merge branch" 或 "Merge remote-tracking branch" 相关的提交。
-4.  报告标题或开头应明确指出报告的时间范围是 %s 到 %s。
-5.  语言为简体中文。
-
-Commit 记录:
-%s
-
-请生成日报内容：`, since, until, since, until, commitsList)
-	case "zh-TW":
-		return fmt.Sprintf(`請根據以下 Git commit 記錄（格式為 "- YYYY-MM-DD -- Commit Subject"），為日期範圍 %s 至 %s 總結生成一份簡潔的工作日報。
-
-要求：
-1.  使用 Markdown 格式。
-2.  按日期**總結**當天完成的主要工作，**不要**羅列單個 commit message。
-3.  忽略所有 "Merge branch" 或 "Merge remote-tracking branch" 相關的提交。
-4.  報告標題或開頭應明確指出報告的時間範圍是 %s 到 %s。
-5.  語言為繁體中文。
-
-Commit 記錄:
-%s
-
-請生成日報內容：`, since, until, since, until, commitsList)
-	default:
-		return fmt.Sprintf(`Please summarize the following Git commit records (formatted as "- YYYY-MM-DD -- Commit Subject") into a concise work report for the period %s to %s.
-
-Requirements:
-1.  Use Markdown format.
-2.  Summarize the main work completed **per day**. **Do not** list individual commit messages.
-3.  Ignore any commits related to "Merge branch" or "Merge remote-tracking branch".
-4.  The report title or beginning should clearly state the reporting period is from %s to %s.
-5.  The language should be English.
-
-Commit Records:
-%s
-
-Please generate the report content:`, since, until, since, until, commitsList)
-	}
+	return reportPrompt(p.language, info, since, until)
 }
 
 // GetCommitTypes 返回指定语言的提交类型
 func (p *OpenAIProvider) GetCommitTypes() []CommitType {
-	types, ok := commitTypes[p.language]
-	if !ok {
-		return commitTypes["en"]
-	}
-	return types
+	return commitStyle{p.language, p.types}.commitTypes()
 }
 
 // GetSystemPrompt 根据语言返回系统提示
 func (p *OpenAIProvider) GetSystemPrompt() string {
-	// 获取提交类型列表
-	types := p.GetCommitTypes()
-
-	// 构建类型说明
-	var typeDesc string
-	for _, t := range types {
-		typeDesc += fmt.Sprintf("- %s: %s\n", t.Type, t.Description)
-	}
-
-	switch p.language {
-	case "zh-CN":
-		return fmt.Sprintf(`您是一个帮助生成标准化git提交信息的助手。
-请严格遵循以下提交信息格式规则：
-
-1. 格式：<类型>(<范围>): <主题>
-
-<正文>
-
-<脚注>
-
-2. 类型必须是以下之一：
-%s
-3. 范围：可选，描述影响的区域（如：router、auth、db）
-4. 主题：简短摘要（不超过50个字符）
-5. 正文：详细说明（每行不超过72个字符）
-6. 脚注：可选，用于说明重大变更或引用问题编号
-
-示例：
-feat(认证): 实现JWT认证系统
-
-添加基于JWT的认证系统，支持刷新令牌
-- 实现令牌生成和验证
-- 添加用户会话管理
-- 设置安全Cookie处理
-
-重大变更：需要新的认证头
-修复 #123`, typeDesc)
-
-	case "zh-TW":
-		return fmt.Sprintf(`您是一個幫助生成標準化git提交信息的助手。
-請嚴格遵循以下提交信息格式規則：
-
-1. 格式：<類型>(<範圍>): <主題>
-
-<正文>
-
-<腳註>
-
-2. 類型必須是以下之一：
-%s
-3. 範圍：可選，描述影響的區域（如：router、auth、db）
-4. 主題：簡短摘要（不超過50個字符）
-5. 正文：詳細說明（每行不超過72個字符）
-6. 腳註：可選，用於說明重大變更或引用問題編號
-
-示例：
-feat(認證): 實現JWT認證系統
-
-添加基於JWT的認證系統，支持刷新令牌
-- 實現令牌生成和驗證
-- 添加用戶會話管理
-- 設置安全Cookie處理
-
-重大變更：需要新的認證頭
-修復 #123`, typeDesc)
-
-	default:
-		return fmt.Sprintf(`You are a helpful assistant that generates standardized git commit messages.
-Follow these strict rules for commit message format:
-
-1. Format: <type>(<scope>): <subject>
-
-<body>
-
-<footer>
-
-2. Types must be one of:
-%s
-3. Scope: Optional, describes the affected area (e.g., router, auth, db)
-4. Subject: Short summary (50 chars or less)
-5. Body: Detailed explanation (72 chars per line)
-6. Footer: Optional, for breaking changes or issue references
-
-Example:
-feat(auth): implement JWT authentication
-
-Add JWT-based authentication system with refresh tokens
-- Implement token generation and validation
-- Add user session management
-- Set up secure cookie handling
-
-BREAKING CHANGE: New authentication headers required
-Fixes #123`, typeDesc)
-	}
+	return systemPrompt(commitStyle{p.language, p.types})
 }
 
-// DefaultMaxDiffLength 默认最大 diff 字符数（约 8000 字符 ≈ 2000 tokens）
-const DefaultMaxDiffLength = 8000
-
-// MaxLinesPerFile 每个文件最大保留的变更行数
-const MaxLinesPerFile = 50
-
 // TruncateDiff 智能截断过长的 diff 内容
-// 保留文件头信息，对每个文件的变更内容进行截断
 func (p *OpenAIProvider) TruncateDiff(diff string, maxLength int) string {
-	if diff == "" {
-		return ""
-	}
+	return truncateDiff(diff, maxLength)
+}
 
-	// 如果 diff 小于限制，直接返回
-	if len(diff) <= maxLength {
-		return diff
-	}
+// GenerateCommitMessage 使用 OpenAI API 生成提交消息
+func (p *OpenAIProvider) GenerateCommitMessage(ctx context.Context, info *CommitInfo) (*CommitMessage, error) {
+	return generateCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, info, p.diff, nil)
+}
 
-	// 按文件分割 diff（使用 "diff --git" 作为分隔符）
-	const diffSeparator = "diff --git"
-	parts := strings.Split(diff, diffSeparator)
+// GenerateCommitMessageStream 使用 OpenAI API 流式生成提交消息
+func (p *OpenAIProvider) GenerateCommitMessageStream(ctx context.Context, info *CommitInfo, onToken func(token string)) (*CommitMessage, error) {
+	return generateCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, info, p.diff, onToken)
+}
 
-	if len(parts) <= 1 {
-		// 单个文件或无法识别格式，直接截断
-		return truncateSingleDiff(diff, maxLength)
-	}
+// RefineCommitMessage 根据用户反馈修改提交消息
+func (p *OpenAIProvider) RefineCommitMessage(ctx context.Context, previous *CommitMessage, feedback string, onToken func(token string)) (*CommitMessage, error) {
+	return refineCommitMessage(ctx, withRetry(p, p.retry), commitStyle{p.language, p.types}, previous, feedback, onToken)
+}
 
-	// 计算文件数量
-	numFiles := len(parts) - 1 // 第一个元素是空的或非 diff 内容
-	if numFiles <= 0 {
-		return truncateSingleDiff(diff, maxLength)
-	}
+// GenerateDailyReport 使用 OpenAI API 生成日报
+func (p *OpenAIProvider) GenerateDailyReport(ctx context.Context, info *ReportInfo, since, until string) (string, error) {
+	return generateDailyReport(ctx, withRetry(p, p.retry), p.language, info, since, until)
+}
 
-	// 预留 100 字符给截断提示
-	availableLength := maxLength - 100
-	if availableLength < 200 {
-		availableLength = 200
+// chat 将通用请求转换为 OpenAI Chat Completions 请求
+func (p *OpenAIProvider) chat(ctx context.Context, req *chatRequest) (*chatResponse, error) {
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: req.System,
+		})
 	}
-
-	var result strings.Builder
-	truncated := false
-	currentLength := 0
-
-	for i, part := range parts {
-		if i == 0 {
-			// 跳过第一个空元素
-			if strings.TrimSpace(part) != "" {
-				result.WriteString(part)
-				currentLength += len(part)
-			}
-			continue
-		}
-
-		fileDiff := diffSeparator + part
-
-		// 检查是否还有足够空间
-		remainingSpace := availableLength - currentLength
-		if remainingSpace <= 100 {
-			// 空间不足，添加截断提示后退出
-			truncated = true
-			break
-		}
-
-		if len(fileDiff) <= remainingSpace {
-			// 这个文件完整放入
-			result.WriteString(fileDiff)
-			currentLength += len(fileDiff)
-		} else {
-			// 需要截断这个文件
-			truncatedPart := truncateFileDiff(fileDiff, remainingSpace)
-			result.WriteString(truncatedPart)
-			truncated = true
-			break
-		}
+	for _, m := range req.Messages {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    m.Role,
+			Content: m.Content,
+		})
 	}
 
-	finalResult := result.String()
-
-	// 如果有截断，添加提示
-	if truncated {
-		finalResult = strings.TrimRight(finalResult, "\n") + "\n\n... [truncated: diff too large] ...\n"
+	request := openai.ChatCompletionRequest{
+		Model:       p.model,
+		Messages:    messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
 	}
-
-	return finalResult
-}
-
-// truncateSingleDiff 截断单个 diff 内容
-func truncateSingleDiff(diff string, maxLength int) string {
-	if len(diff) <= maxLength {
-		return diff
+	if req.Schema != nil {
+		request.ResponseFormat = p.responseFormat(req.Schema)
 	}
-
-	// 保留前面部分，添加截断提示
-	truncateAt := maxLength - 50 // 留出空间给截断提示
-	if truncateAt < 100 {
-		truncateAt = 100
+	if req.OnToken != nil {
+		return p.chatStream(ctx, request, req.OnToken)
 	}
 
-	// 尝试在行边界截断
-	lastNewline := strings.LastIndex(diff[:truncateAt], "\n")
-	if lastNewline > 0 {
-		truncateAt = lastNewline
+	resp, err := p.client.CreateChatCompletion(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("请求 OpenAI API 失败: %w", err)
 	}
 
-	return diff[:truncateAt] + "\n\n... [truncated: diff too large] ...\n"
+	result := &chatResponse{
+		Usage: Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+	}
+	if len(resp.Choices) > 0 {
+		result.Content = resp.Choices[0].Message.Content
+	}
+	return result, nil
 }
 
-// truncateFileDiff 截断单个文件的 diff，保留头部信息
-func truncateFileDiff(fileDiff string, maxLength int) string {
-	if len(fileDiff) <= maxLength {
-		return fileDiff
+// responseFormat 返回结构化输出使用的 response_format，不支持时返回 nil，仅依靠提示词要求 JSON
+func (p *OpenAIProvider) responseFormat(schema *jsonSchema) *openai.ChatCompletionResponseFormat {
+	switch {
+	case p.provider == "azure":
+		// 部署名称无法判断模型，旧版本 API 也不支持 json_schema
+		return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	case p.baseURL != "" && !strings.HasPrefix(p.baseURL, "https://api.openai.com"):
+		// OpenAI 兼容服务不一定支持 response_format
+		return nil
+	case supportsJSONSchema(p.model):
+		return &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   commitMessageToolName,
+				Schema: marshalSchema(schema),
+				Strict: true,
+			},
+		}
+	case strings.HasPrefix(p.model, "gpt-4-turbo"), strings.HasPrefix(p.model, "gpt-3.5-turbo"):
+		return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	default:
+		return nil
 	}
+}
 
-	lines := strings.Split(fileDiff, "\n")
-	var result strings.Builder
-	currentLength := 0
-
-	for i, line := range lines {
-		lineWithNewline := line + "\n"
-		newLength := currentLength + len(lineWithNewline)
-
-		// 保留头部信息（前几行通常是头部）
-		isHeader := strings.HasPrefix(line, "diff --git") ||
-			strings.HasPrefix(line, "---") ||
-			strings.HasPrefix(line, "+++") ||
-			strings.HasPrefix(line, "@@") ||
-			strings.HasPrefix(line, "index ")
-
-		if isHeader || i < 5 {
-			// 总是保留头部行
-			result.WriteString(lineWithNewline)
-			currentLength = newLength
-			continue
+// supportsJSONSchema 判断 OpenAI 模型是否支持 json_schema 结构化输出
+func supportsJSONSchema(model string) bool {
+	model = strings.ToLower(model)
+	for _, unsupported := range []string{"gpt-4o-2024-05-13", "o1-mini", "o1-preview"} {
+		if strings.HasPrefix(model, unsupported) {
+			return false
 		}
-
-		// 检查是否超出限制
-		if newLength > maxLength-50 {
-			// 添加截断提示
-			remaining := len(lines) - i
-			result.WriteString(fmt.Sprintf("\n... [truncated: %d more lines] ...\n", remaining))
-			break
+	}
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-5", "o1", "o3", "o4"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
 		}
-
-		result.WriteString(lineWithNewline)
-		currentLength = newLength
 	}
-
-	return result.String()
+	return false
 }
 
-// GenerateCommitMessage 使用 OpenAI API 生成提交消息
-func (p *OpenAIProvider) GenerateCommitMessage(ctx context.Context, info *CommitInfo) (*CommitMessage, error) {
-	// 截断过长的 diff 内容
-	truncatedDiff := p.TruncateDiff(info.DiffContent, DefaultMaxDiffLength)
-	truncatedInfo := &CommitInfo{
-		FilesChanged: info.FilesChanged,
-		DiffContent:  truncatedDiff,
-		BranchName:   info.BranchName,
+// chatStream 使用 CreateChatCompletionStream 流式获取回复
+func (p *OpenAIProvider) chatStream(ctx context.Context, request openai.ChatCompletionRequest, onToken func(string)) (*chatResponse, error) {
+	request.Stream = true
+	// 旧版本 Azure API 不支持 stream_options，仅对 OpenAI 请求用量统计
+	if p.provider != "azure" {
+		request.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
 	}
 
-	// 准备用户提示
-	userPrompt := p.GetUserPrompt(truncatedInfo, p.BuildFilesList(truncatedInfo.FilesChanged))
-	systemPrompt := p.GetSystemPrompt()
-
-	// 创建聊天请求
-	resp, err := p.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: p.model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: systemPrompt,
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: userPrompt,
-				},
-			},
-			Temperature: 0.7,
-			MaxTokens:   1500,
-		},
-	)
-
+	stream, err := p.client.CreateChatCompletionStream(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("请求 OpenAI API 失败: %w", err)
 	}
+	defer stream.Close()
 
-	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
-		return nil, fmt.Errorf("OpenAI 未返回有效的提交信息内容")
-	}
-
-	// 清理响应内容中的Markdown格式标记
-	content := resp.Choices[0].Message.Content
-	content = p.CleanMarkdownFormatting(content)
+	result := &chatResponse{}
+	var content strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取 OpenAI 流式响应失败: %w", err)
+		}
 
-	// 分割标题和正文
-	parts := strings.SplitN(content, "\n\n", 2)
-	message := &CommitMessage{
-		Title: strings.TrimSpace(parts[0]),
-	}
-	if len(parts) > 1 {
-		message.Body = strings.TrimSpace(parts[1])
+		if chunk.Usage != nil {
+			result.Usage = Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			token := chunk.Choices[0].Delta.Content
+			content.WriteString(token)
+			onToken(token)
+		}
 	}
 
-	return message, nil
+	result.Content = content.String()
+	return result, nil
 }
 
-// GenerateDailyReport 使用 OpenAI API 生成日报
-func (p *OpenAIProvider) GenerateDailyReport(ctx context.Context, info *ReportInfo, since, until string) (string, error) {
-	// 准备用户提示
-	userPrompt := p.GetUserPromptForReport(info, since, until)
-
-	// 创建聊天请求
-	resp, err := p.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: p.model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: userPrompt,
-				},
-			},
-			Temperature: 0.7,
-			MaxTokens:   2000,
-		},
-	)
-
-	if err != nil {
-		return "", fmt.Errorf("请求 OpenAI API 失败: %w", err)
+func (p *OpenAIProvider) displayName() string {
+	if factory, ok := Lookup(p.provider); ok {
+		return factory.DisplayName
 	}
+	return "OpenAI"
+}
 
-	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
-		return "", fmt.Errorf("OpenAI 未返回有效的日报内容")
-	}
-
-	reportContent := p.CleanMarkdownFormatting(resp.Choices[0].Message.Content)
-
-	return reportContent, nil
+func (p *OpenAIProvider) modelName() string {
+	return p.model
 }
@@ -287,3 +287,22 @@ diff --git a/file3.go b/file3.go
 		t.Error("应保留所有 3 个文件的 diff")
 	}
 }
+
+func TestOpenAIProvider_DisplayName(t *testing.T) {
+	testCases := []struct {
+		provider string
+		expected string
+	}{
+		{"openai", "OpenAI"},
+		{"azure", "Azure OpenAI"},
+	}
+	for _, tc := range testCases {
+		p, err := newOpenAIProvider(tc.provider, ProviderConfig{APIKey: "test-key", BaseURL: "https://example.openai.azure.com", Model: "gpt-4o"})
+		if err != nil {
+			t.Fatalf("创建 Provider 失败: %v", err)
+		}
+		if got := p.displayName(); got != tc.expected {
+			t.Errorf("%s: 期望名称 '%s', 实际='%s'", tc.provider, tc.expected, got)
+		}
+	}
+}
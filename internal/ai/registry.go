@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigField 表示提供商声明的配置项，取值与配置文件中的 JSON 键一致
+type ConfigField string
+
+const (
+	FieldAPIKey  ConfigField = "api_key"
+	FieldBaseURL ConfigField = "base_url"
+	FieldModel   ConfigField = "model"
+)
+
+// fieldHints 配置项的名称及对应的 config 命令参数，用于生成错误提示
+var fieldHints = map[ConfigField]struct {
+	Label string
+	Flag  string
+}{
+	FieldAPIKey:  {"API 密钥", "--api-key YOUR_API_KEY"},
+	FieldBaseURL: {"Base URL", "--base-url YOUR_BASE_URL"},
+	FieldModel:   {"模型名称", "--model YOUR_MODEL"},
+}
+
+// ProviderConfig 创建 Provider 所需的配置
+type ProviderConfig struct {
+	APIKey          string
+	BaseURL         string
+	Model           string
+	Language        string
+	AzureAPIVersion string
+	// MaxRetries 遇到限流或服务端错误时的最大重试次数，0 表示不重试
+	MaxRetries int
+	// RetryBaseDelay 首次重试前的等待时间，为 0 时使用 DefaultRetryBaseDelay
+	RetryBaseDelay time.Duration
+	// DiffMode diff 超出预算时的处理方式，为空时截断
+	DiffMode DiffMode
+	// DiffBudget 单次请求中 diff 的最大 token 数，为 0 时按模型的上下文窗口计算
+	DiffBudget int
+	// SummaryConcurrency 分块总结时的最大并发请求数，为 0 时使用 DefaultSummaryConcurrency
+	SummaryConcurrency int
+	// CommitTypes 允许的提交类型，为空时使用 CommitTypeNames 返回的默认类型
+	CommitTypes []string
+}
+
+// value 返回配置项的值
+func (c ProviderConfig) value(field ConfigField) string {
+	switch field {
+	case FieldAPIKey:
+		return c.APIKey
+	case FieldBaseURL:
+		return c.BaseURL
+	case FieldModel:
+		return c.Model
+	default:
+		return ""
+	}
+}
+
+// Factory 描述一个可注册的提供商
+type Factory struct {
+	// DisplayName 用于提示信息的名称，如 "Azure OpenAI"
+	DisplayName string
+	// Required 创建实例前必须配置的字段
+	Required []ConfigField
+	// DefaultModel 未指定模型时使用的默认模型
+	DefaultModel string
+	// APIKeyEnv 未配置 API Key 时读取的常用环境变量，如 OPENAI_API_KEY
+	APIKeyEnv string
+	// New 根据配置创建 Provider 实例
+	New func(cfg ProviderConfig) (Provider, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register 注册提供商，重复注册同一名称会 panic
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory.New == nil {
+		panic(fmt.Sprintf("ai: 提供商 %s 缺少 New 函数", name))
+	}
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("ai: 提供商 %s 重复注册", name))
+	}
+	registry[name] = factory
+}
+
+// Lookup 查找已注册的提供商
+func Lookup(name string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, ok := registry[name]
+	return factory, ok
+}
+
+// Providers 返回所有已注册的提供商名称（按字母排序）
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateConfig 按提供商声明的必填字段校验配置，并生成可操作的错误提示
+func ValidateConfig(name string, cfg ProviderConfig) error {
+	factory, ok := Lookup(name)
+	if !ok {
+		return fmt.Errorf("不支持的提供商: %s（可选: %s）", name, strings.Join(Providers(), ", "))
+	}
+
+	for _, field := range factory.Required {
+		if cfg.value(field) == "" {
+			hint := fieldHints[field]
+			return fmt.Errorf("未配置 %s %s，请先使用 'aicommit config %s' 配置", factory.DisplayName, hint.Label, hint.Flag)
+		}
+	}
+	return nil
+}
+
+// NewProvider 根据注册表创建 Provider 实例，provider 为空时使用 OpenAI
+func NewProvider(provider string, cfg ProviderConfig) (Provider, error) {
+	if provider == "" {
+		provider = "openai"
+	}
+
+	if err := ValidateConfig(provider, cfg); err != nil {
+		return nil, err
+	}
+
+	factory, _ := Lookup(provider)
+	if cfg.Model == "" {
+		cfg.Model = factory.DefaultModel
+	}
+	return factory.New(cfg)
+}
@@ -0,0 +1,134 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fakeProvider 用于测试注册表的 Provider
+type fakeProvider struct {
+	cfg ProviderConfig
+}
+
+func (f *fakeProvider) GenerateCommitMessage(ctx context.Context, info *CommitInfo) (*CommitMessage, error) {
+	return &CommitMessage{Title: "chore: fake"}, nil
+}
+
+func (f *fakeProvider) GenerateCommitMessageStream(ctx context.Context, info *CommitInfo, onToken func(string)) (*CommitMessage, error) {
+	return f.GenerateCommitMessage(ctx, info)
+}
+
+func (f *fakeProvider) RefineCommitMessage(ctx context.Context, previous *CommitMessage, feedback string, onToken func(string)) (*CommitMessage, error) {
+	return &CommitMessage{Title: "chore: refined"}, nil
+}
+
+func (f *fakeProvider) GenerateDailyReport(ctx context.Context, info *ReportInfo, since, until string) (string, error) {
+	return "", nil
+}
+
+// registerForTest 注册测试用提供商，并在测试结束后移除
+func registerForTest(t *testing.T, name string, factory Factory) {
+	t.Helper()
+	Register(name, factory)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, name)
+		registryMu.Unlock()
+	})
+}
+
+func TestRegistry_BuiltinProviders(t *testing.T) {
+	names := strings.Join(Providers(), ",")
+	for _, name := range []string{"anthropic", "azure", "gemini", "ollama", "openai"} {
+		if !strings.Contains(names, name) {
+			t.Errorf("内置提供商 '%s' 未注册, 已注册=%s", name, names)
+		}
+	}
+}
+
+func TestRegistry_PluggableProvider(t *testing.T) {
+	registerForTest(t, "test-fake", Factory{
+		DisplayName:  "Fake",
+		DefaultModel: "fake-1",
+		New: func(cfg ProviderConfig) (Provider, error) {
+			return &fakeProvider{cfg: cfg}, nil
+		},
+	})
+
+	p, err := NewProvider("test-fake", ProviderConfig{Language: "en"})
+	if err != nil {
+		t.Fatalf("创建 Provider 失败: %v", err)
+	}
+	fp, ok := p.(*fakeProvider)
+	if !ok {
+		t.Fatalf("期望 *fakeProvider, 实际=%T", p)
+	}
+	if fp.cfg.Model != "fake-1" {
+		t.Errorf("未指定模型时应使用声明的默认模型, 实际='%s'", fp.cfg.Model)
+	}
+}
+
+func TestRegistry_DuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("重复注册应 panic")
+		}
+	}()
+	Register("openai", Factory{New: func(cfg ProviderConfig) (Provider, error) { return nil, nil }})
+}
+
+func TestValidateConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		provider string
+		cfg      ProviderConfig
+		expected string
+	}{
+		{
+			name:     "OpenAI 缺少 API 密钥",
+			provider: "openai",
+			cfg:      ProviderConfig{},
+			expected: "未配置 OpenAI API 密钥，请先使用 'aicommit config --api-key YOUR_API_KEY' 配置",
+		},
+		{
+			name:     "Azure 缺少 Base URL",
+			provider: "azure",
+			cfg:      ProviderConfig{APIKey: "key"},
+			expected: "未配置 Azure OpenAI Base URL，请先使用 'aicommit config --base-url YOUR_BASE_URL' 配置",
+		},
+		{
+			name:     "Azure 缺少模型",
+			provider: "azure",
+			cfg:      ProviderConfig{APIKey: "key", BaseURL: "https://example.openai.azure.com"},
+			expected: "--model YOUR_MODEL",
+		},
+		{
+			name:     "未知提供商",
+			provider: "unknown",
+			cfg:      ProviderConfig{},
+			expected: "不支持的提供商: unknown",
+		},
+		{
+			name:     "Ollama 无必填字段",
+			provider: "ollama",
+			cfg:      ProviderConfig{},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateConfig(tc.provider, tc.cfg)
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("期望校验通过, 实际错误=%v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("期望错误包含 '%s', 实际=%v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// DefaultMaxRetries 默认最大重试次数
+	DefaultMaxRetries = 3
+	// DefaultRetryBaseDelay 默认的首次重试等待时间，之后按指数增长
+	DefaultRetryBaseDelay = time.Second
+	// maxRetryWait 单次调用累计等待时间上限，超过后直接返回错误
+	maxRetryWait = 60 * time.Second
+)
+
+// RetryPolicy API 调用的重试策略
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxWait    time.Duration
+}
+
+// DefaultRetryPolicy 返回默认重试策略
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultRetryBaseDelay,
+		MaxWait:    maxRetryWait,
+	}
+}
+
+// retryPolicyFrom 根据配置生成重试策略，MaxRetries 为 0 表示不重试
+func retryPolicyFrom(cfg ProviderConfig) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = cfg.MaxRetries
+	if cfg.RetryBaseDelay > 0 {
+		policy.BaseDelay = cfg.RetryBaseDelay
+	}
+	return policy
+}
+
+// retryableStatus 判断 HTTP 状态码是否值得重试
+// 429 限流、408 超时、5xx 服务端错误（529 为 Anthropic 过载）
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, 529:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable 判断错误是否为可重试的临时错误
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.HTTPStatusCode)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return retryableStatus(reqErr.HTTPStatusCode)
+	}
+	var anthropicErr *AnthropicAPIError
+	if errors.As(err, &anthropicErr) {
+		return retryableStatus(anthropicErr.StatusCode)
+	}
+	var geminiErr *GeminiAPIError
+	if errors.As(err, &geminiErr) {
+		return retryableStatus(geminiErr.StatusCode)
+	}
+	var ollamaErr *OllamaAPIError
+	if errors.As(err, &ollamaErr) {
+		return retryableStatus(ollamaErr.StatusCode)
+	}
+
+	// 网络层的超时和连接被重置同样视为临时错误
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryHint 记录最近一次失败响应的 Retry-After
+type retryHint struct {
+	mu         sync.Mutex
+	retryAfter time.Duration
+}
+
+type retryHintKey struct{}
+
+// retryHintTransport 在响应失败时把 Retry-After 头写入请求上下文中的 retryHint
+// go-openai 的错误类型不包含响应头，因此在传输层获取
+type retryHintTransport struct {
+	transport http.RoundTripper
+}
+
+func (t *retryHintTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil || resp.StatusCode < 400 {
+		return resp, err
+	}
+
+	if hint, ok := req.Context().Value(retryHintKey{}).(*retryHint); ok {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			hint.mu.Lock()
+			hint.retryAfter = d
+			hint.mu.Unlock()
+		}
+	}
+	return resp, nil
+}
+
+// parseRetryAfter 解析 Retry-After 头，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// backoff 计算第 attempt 次重试（从 0 开始）的等待时间：指数增长并加入少量随机抖动
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || delay > p.MaxWait {
+		delay = p.MaxWait
+	}
+	if jitter := int64(delay / 5); jitter > 0 {
+		delay += time.Duration(rand.Int63n(jitter))
+	}
+	return delay
+}
+
+// retryClient 为 chatClient 增加重试能力
+type retryClient struct {
+	chatClient
+	policy RetryPolicy
+}
+
+// withRetry 用重试策略包装 chatClient
+func withRetry(c chatClient, policy RetryPolicy) chatClient {
+	if policy.MaxRetries <= 0 {
+		return c
+	}
+	return &retryClient{chatClient: c, policy: policy}
+}
+
+// chat 发送请求，遇到可重试错误时按策略等待后重试
+// 流式请求一旦已经输出内容就不再重试，避免重复输出
+func (c *retryClient) chat(ctx context.Context, req *chatRequest) (*chatResponse, error) {
+	var waited time.Duration
+	streamed := false
+
+	attemptReq := *req
+	if req.OnToken != nil {
+		attemptReq.OnToken = func(token string) {
+			streamed = true
+			req.OnToken(token)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		hint := &retryHint{}
+		resp, err := c.chatClient.chat(context.WithValue(ctx, retryHintKey{}, hint), &attemptReq)
+		if err == nil || streamed || attempt >= c.policy.MaxRetries || !IsRetryable(err) {
+			return resp, err
+		}
+
+		delay := c.policy.backoff(attempt)
+		hint.mu.Lock()
+		if hint.retryAfter > 0 {
+			delay = hint.retryAfter
+		}
+		hint.mu.Unlock()
+
+		if waited+delay > c.policy.MaxWait {
+			return resp, err
+		}
+		waited += delay
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
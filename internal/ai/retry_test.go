@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// newFlakyOpenAIServer 创建测试服务器：前 failures 次请求返回 status，之后返回正常结果
+func newFlakyOpenAIServer(t *testing.T, failures int32, status int, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"error":{"message":"rate limited","type":"rate_limit_error"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{
+				Message: openai.ChatCompletionMessage{Role: "assistant", Content: "feat: add retry"},
+			}},
+		})
+	}))
+}
+
+func newRetryTestProvider(t *testing.T, baseURL string, maxRetries int) Provider {
+	t.Helper()
+	p, err := NewProvider("openai", ProviderConfig{
+		APIKey:         "test-key",
+		BaseURL:        baseURL,
+		Language:       "en",
+		MaxRetries:     maxRetries,
+		RetryBaseDelay: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("创建 Provider 失败: %v", err)
+	}
+	return p
+}
+
+func TestRetryOnRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	server := newFlakyOpenAIServer(t, 1, http.StatusTooManyRequests, &calls)
+	defer server.Close()
+
+	msg, err := newRetryTestProvider(t, server.URL, 3).GenerateCommitMessage(context.Background(), &CommitInfo{})
+	if err != nil {
+		t.Fatalf("期望重试后成功, 实际错误=%v", err)
+	}
+	if msg.Title != "feat: add retry" {
+		t.Errorf("期望 Title='feat: add retry', 实际='%s'", msg.Title)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("期望请求 2 次, 实际=%d", calls.Load())
+	}
+}
+
+func TestRetryGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := newFlakyOpenAIServer(t, 10, http.StatusServiceUnavailable, &calls)
+	defer server.Close()
+
+	_, err := newRetryTestProvider(t, server.URL, 2).GenerateCommitMessage(context.Background(), &CommitInfo{})
+	if err == nil {
+		t.Fatal("期望返回错误")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("期望请求 3 次（1 次 + 2 次重试）, 实际=%d", calls.Load())
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	server := newFlakyOpenAIServer(t, 1, http.StatusBadRequest, &calls)
+	defer server.Close()
+
+	_, err := newRetryTestProvider(t, server.URL, 3).GenerateCommitMessage(context.Background(), &CommitInfo{})
+	if err == nil {
+		t.Fatal("期望返回错误")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("400 错误不应重试, 实际请求=%d 次", calls.Load())
+	}
+}
+
+func TestRetryDisabled(t *testing.T) {
+	var calls atomic.Int32
+	server := newFlakyOpenAIServer(t, 1, http.StatusTooManyRequests, &calls)
+	defer server.Close()
+
+	if _, err := newRetryTestProvider(t, server.URL, 0).GenerateCommitMessage(context.Background(), &CommitInfo{}); err == nil {
+		t.Fatal("关闭重试时期望直接返回错误")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("期望请求 1 次, 实际=%d", calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"Wed, 01 Jan 2025 00:00:30 GMT", 30 * time.Second, true},
+		{"Tue, 31 Dec 2024 23:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = (%v, %v), 期望 (%v, %v)", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// maxStreamLineSize 单行流式数据的最大长度
+const maxStreamLineSize = 1024 * 1024
+
+// readSSE 逐条读取 Server-Sent Events 的 data 字段并回调 fn
+// 遇到 "[DONE]" 结束标记或读到 EOF 时返回
+func readSSE(r io.Reader, fn func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLineSize)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			// 忽略 event:、id: 和注释行
+			continue
+		}
+
+		data := bytes.TrimSpace(line[len("data:"):])
+		if len(data) == 0 {
+			continue
+		}
+		if string(data) == "[DONE]" {
+			return nil
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// readJSONLines 逐行读取换行分隔的 JSON 流（如 Ollama）并回调 fn
+func readJSONLines(r io.Reader, fn func(line []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLineSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,432 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SimonGino/aicommit/internal/lint"
+)
+
+// commitMessageToolName 通过工具调用获取结构化提交信息时使用的工具名称
+const commitMessageToolName = "commit_message"
+
+// jsonSchema 描述结构化输出的 JSON Schema 子集，properties 按声明顺序序列化
+type jsonSchema struct {
+	Type                 string         `json:"type"`
+	Description          string         `json:"description,omitempty"`
+	Enum                 []string       `json:"enum,omitempty"`
+	Properties           jsonProperties `json:"properties,omitempty"`
+	Items                *jsonSchema    `json:"items,omitempty"`
+	Required             []string       `json:"required,omitempty"`
+	AdditionalProperties *bool          `json:"additionalProperties,omitempty"`
+	// PropertyOrdering Gemini 按该顺序输出字段
+	PropertyOrdering []string `json:"propertyOrdering,omitempty"`
+}
+
+// jsonProperty 对象的一个字段
+type jsonProperty struct {
+	Name   string
+	Schema *jsonSchema
+}
+
+// jsonProperties 有序的字段列表，模型通常按声明顺序输出字段，流式显示时标题先于正文
+type jsonProperties []jsonProperty
+
+func (p jsonProperties) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(prop.Name)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := json.Marshal(prop.Schema)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(schema)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+func (p *jsonProperties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	*p = nil
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name, _ := token.(string)
+		var schema jsonSchema
+		if err := dec.Decode(&schema); err != nil {
+			return err
+		}
+		*p = append(*p, jsonProperty{Name: name, Schema: &schema})
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// commitMessageSchema 返回结构化提交信息的 JSON Schema
+func commitMessageSchema(style commitStyle) *jsonSchema {
+	types := style.typeNames()
+
+	closed := false
+	schema := &jsonSchema{
+		Type: "object",
+		Properties: jsonProperties{
+			{"type", &jsonSchema{Type: "string", Enum: types}},
+			{"scope", &jsonSchema{Type: "string", Description: "Affected area, empty string if none"}},
+			{"subject", &jsonSchema{Type: "string", Description: "Short summary, 50 chars or less"}},
+			{"body", &jsonSchema{Type: "string", Description: "Detailed explanation, may be empty"}},
+			{"breaking", &jsonSchema{Type: "boolean"}},
+			{"footers", &jsonSchema{Type: "array", Items: &jsonSchema{Type: "string"}, Description: `Footer lines such as "BREAKING CHANGE: ...", empty array if none`}},
+		},
+		AdditionalProperties: &closed,
+	}
+	for _, prop := range schema.Properties {
+		schema.Required = append(schema.Required, prop.Name)
+	}
+	return schema
+}
+
+// geminiSchema 把 JSON Schema 转换为 Gemini responseSchema 支持的形式
+// Gemini 使用大写的类型名，不支持 additionalProperties，字段顺序由 propertyOrdering 指定
+func geminiSchema(s *jsonSchema) *jsonSchema {
+	out := *s
+	out.Type = strings.ToUpper(s.Type)
+	out.AdditionalProperties = nil
+	out.Properties = nil
+	for _, prop := range s.Properties {
+		out.Properties = append(out.Properties, jsonProperty{prop.Name, geminiSchema(prop.Schema)})
+		out.PropertyOrdering = append(out.PropertyOrdering, prop.Name)
+	}
+	if s.Items != nil {
+		out.Items = geminiSchema(s.Items)
+	}
+	return &out
+}
+
+// marshalSchema 序列化 JSON Schema，用于嵌入各提供商的请求体
+func marshalSchema(s *jsonSchema) json.RawMessage {
+	data, err := json.Marshal(s)
+	if err != nil {
+		// schema 是固定结构，序列化不会失败
+		panic(fmt.Sprintf("序列化 JSON Schema 失败: %v", err))
+	}
+	return data
+}
+
+// structuredMessage 模型返回的结构化提交信息
+type structuredMessage struct {
+	Type     string   `json:"type"`
+	Scope    string   `json:"scope"`
+	Subject  string   `json:"subject"`
+	Body     string   `json:"body"`
+	Breaking bool     `json:"breaking"`
+	Footers  []string `json:"footers"`
+}
+
+// footerPattern 匹配 "Token: value" 或 "Token #value" 格式的脚注
+var footerPattern = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[A-Za-z][A-Za-z-]*)(: | #)`)
+
+// validate 检查结构化提交信息的必填字段
+func (m *structuredMessage) validate() error {
+	m.Type = strings.ToLower(strings.TrimSpace(m.Type))
+	m.Scope = strings.TrimSpace(m.Scope)
+	m.Subject = strings.TrimSpace(m.Subject)
+	m.Body = strings.TrimSpace(m.Body)
+
+	if !lint.ValidType(m.Type) {
+		return fmt.Errorf("无效的提交类型: %q", m.Type)
+	}
+	if m.Subject == "" {
+		return fmt.Errorf("提交主题不能为空")
+	}
+	if strings.Contains(m.Subject, "\n") {
+		return fmt.Errorf("提交主题不能包含换行: %q", m.Subject)
+	}
+	if strings.ContainsAny(m.Scope, "()\n") {
+		return fmt.Errorf("无效的提交范围: %q", m.Scope)
+	}
+
+	var footers []string
+	for _, f := range m.Footers {
+		// 与纯文本一样去掉模型随意添加的 issue 引用
+		if f = strings.TrimSpace(f); f != "" && !isIssueReference(f) {
+			footers = append(footers, f)
+		}
+	}
+	m.Footers = footers
+	return nil
+}
+
+// commitMessage 把结构化提交信息转换为 CommitMessage，并拼接标题和正文
+func (m *structuredMessage) commitMessage() *CommitMessage {
+	message := &CommitMessage{
+		Type:     m.Type,
+		Scope:    m.Scope,
+		Subject:  m.Subject,
+		Breaking: m.Breaking || slices.ContainsFunc(m.Footers, isBreakingFooter),
+		Footers:  m.Footers,
+	}
+	message.Title = formatHeader(message.Type, message.Scope, message.Subject, message.Breaking)
+
+	var paragraphs []string
+	if m.Body != "" {
+		paragraphs = append(paragraphs, m.Body)
+	}
+	if len(m.Footers) > 0 {
+		paragraphs = append(paragraphs, strings.Join(m.Footers, "\n"))
+	}
+	message.Body = strings.Join(paragraphs, "\n\n")
+	return message
+}
+
+// formatHeader 拼接 "<type>(<scope>)!: <subject>" 格式的标题
+func formatHeader(typ, scope, subject string, breaking bool) string {
+	var b strings.Builder
+	b.WriteString(typ)
+	if scope != "" {
+		b.WriteString("(" + scope + ")")
+	}
+	if breaking {
+		b.WriteByte('!')
+	}
+	b.WriteString(": " + subject)
+	return b.String()
+}
+
+// isBreakingFooter 判断脚注是否声明了破坏性变更
+func isBreakingFooter(footer string) bool {
+	return strings.HasPrefix(footer, "BREAKING CHANGE") || strings.HasPrefix(footer, "BREAKING-CHANGE")
+}
+
+// parseStructuredMessage 从模型回复中提取 JSON 对象并校验
+// 回复中没有 JSON 对象时 ok 为 false，由调用方按纯文本解析
+func parseStructuredMessage(content string) (message *CommitMessage, ok bool, err error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, false, nil
+	}
+	// 只有以 JSON 开头（允许代码块标记）的回复才视为结构化输出，避免误解析正文中的花括号
+	prefix := strings.TrimSpace(content[:start])
+	if prefix != "" && !strings.HasPrefix(prefix, "```") {
+		return nil, false, nil
+	}
+
+	var structured structuredMessage
+	if err := json.Unmarshal([]byte(content[start:end+1]), &structured); err != nil {
+		return nil, true, fmt.Errorf("解析结构化提交信息失败: %w", err)
+	}
+	if err := structured.validate(); err != nil {
+		return nil, true, err
+	}
+	return structured.commitMessage(), true, nil
+}
+
+// parseHeader 从纯文本提交信息中解析类型、范围和主题，标题不符合约定格式时只填写主题
+func (m *CommitMessage) parseHeader() {
+	header, ok := lint.ParseHeader(m.Title)
+	if !ok {
+		m.Subject = m.Title
+		return
+	}
+	m.Type, m.Scope, m.Breaking, m.Subject = header.Type, header.Scope, header.Breaking, header.Subject
+
+	// 正文最后一段全部是脚注时，提取为 Footers
+	if m.Body == "" {
+		return
+	}
+	paragraphs := strings.Split(m.Body, "\n\n")
+	last := strings.Split(strings.TrimSpace(paragraphs[len(paragraphs)-1]), "\n")
+	for _, line := range last {
+		if !footerPattern.MatchString(line) {
+			return
+		}
+	}
+	m.Footers = last
+	m.Breaking = m.Breaking || slices.ContainsFunc(last, isBreakingFooter)
+}
+
+// structuredStream 从流式返回的 JSON 中提取 subject 和 body 的文本用于实时显示
+// 回复不是 JSON 时原样转发
+type structuredStream struct {
+	onToken func(string)
+
+	started     bool // 已确定回复格式
+	passthrough bool // 回复不是 JSON，原样转发
+	prefix      strings.Builder
+
+	depth    int
+	inString bool
+	escape   bool
+	unicode  []byte // 正在解析的 \uXXXX 转义
+	isKey    bool   // 当前字符串是字段名
+	key      strings.Builder
+	field    string // 当前值所属的字段
+	emitted  bool   // 已输出过文本
+	inBody   bool   // 已开始输出正文
+	pending  []byte // 尚未组成完整 UTF-8 字符的字节
+}
+
+// newStructuredStream 包装 onToken，onToken 为 nil 时返回 nil
+func newStructuredStream(onToken func(string)) func(string) {
+	if onToken == nil {
+		return nil
+	}
+	s := &structuredStream{onToken: onToken}
+	return s.write
+}
+
+func (s *structuredStream) write(token string) {
+	if !s.started {
+		s.prefix.WriteString(token)
+		text := strings.TrimSpace(s.prefix.String())
+		switch {
+		case text == "":
+			return
+		case text[0] == '{' || text[0] == '`':
+			s.started = true
+			token = s.prefix.String()
+		default:
+			s.started, s.passthrough = true, true
+			s.onToken(s.prefix.String())
+			return
+		}
+	} else if s.passthrough {
+		s.onToken(token)
+		return
+	}
+
+	var out []byte
+	for i := 0; i < len(token); i++ {
+		out = s.consume(out, token[i])
+	}
+	s.flush(out)
+}
+
+// consume 处理一个字节，返回追加了需要输出的文本的 out
+func (s *structuredStream) consume(out []byte, c byte) []byte {
+	if !s.inString {
+		switch c {
+		case '{', '[':
+			s.depth++
+		case '}', ']':
+			s.depth--
+		case '"':
+			s.inString = true
+			// 顶层对象中冒号之后的字符串是值，其余是字段名
+			s.isKey = s.depth == 1 && s.field == ""
+			s.key.Reset()
+		case ':':
+			if s.depth == 1 {
+				s.field = s.key.String()
+			}
+		case ',':
+			if s.depth == 1 {
+				s.field = ""
+			}
+		}
+		return out
+	}
+
+	if s.unicode != nil {
+		s.unicode = append(s.unicode, c)
+		if len(s.unicode) == 4 {
+			var r rune
+			if _, err := fmt.Sscanf(string(s.unicode), "%04x", &r); err == nil {
+				out = s.appendText(out, string(r))
+			}
+			s.unicode = nil
+		}
+		return out
+	}
+
+	if s.escape {
+		s.escape = false
+		switch c {
+		case 'n':
+			return s.appendText(out, "\n")
+		case 't':
+			return s.appendText(out, "\t")
+		case 'r', 'b', 'f':
+			return out
+		case 'u':
+			s.unicode = []byte{}
+			return out
+		default:
+			return s.appendText(out, string([]byte{c}))
+		}
+	}
+
+	switch c {
+	case '\\':
+		s.escape = true
+	case '"':
+		s.inString = false
+	default:
+		if s.isKey {
+			s.key.WriteByte(c)
+		} else {
+			out = s.appendText(out, string([]byte{c}))
+		}
+	}
+	return out
+}
+
+// emitting 当前值是否需要输出
+func (s *structuredStream) emitting() bool {
+	return s.depth == 1 && (s.field == "subject" || s.field == "body")
+}
+
+func (s *structuredStream) appendText(out []byte, text string) []byte {
+	if s.isKey {
+		s.key.WriteString(text)
+		return out
+	}
+	if !s.emitting() {
+		return out
+	}
+	// 标题和正文之间空一行
+	if s.field == "body" && !s.inBody {
+		s.inBody = true
+		if s.emitted {
+			out = append(out, "\n\n"...)
+		}
+	}
+	s.emitted = true
+	return append(out, text...)
+}
+
+// flush 输出完整的 UTF-8 字符，不完整的字节留到下一次
+func (s *structuredStream) flush(out []byte) {
+	data := append(s.pending, out...)
+	n := len(data)
+	for n > 0 && !utf8.Valid(data[:n]) && len(data)-n < utf8.UTFMax {
+		n--
+	}
+	if !utf8.Valid(data[:n]) {
+		n = len(data)
+	}
+	s.pending = append([]byte(nil), data[n:]...)
+	if n > 0 {
+		s.onToken(string(data[:n]))
+	}
+}
@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseCommitMessage_Structured(t *testing.T) {
+	content := "```json\n" + `{"type":"Feat","scope":"api","subject":"add users endpoint","body":"- list users\n- create user","breaking":true,"footers":["Refs #7","Fixes #123"]}` + "\n```"
+
+	msg, err := parseCommitMessage(content)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if msg.Type != "feat" || msg.Scope != "api" || msg.Subject != "add users endpoint" || !msg.Breaking {
+		t.Errorf("结构化字段解析错误: %+v", msg)
+	}
+	if msg.Title != "feat(api)!: add users endpoint" {
+		t.Errorf("标题拼接错误: %q", msg.Title)
+	}
+	if msg.Body != "- list users\n- create user\n\nRefs #7" {
+		t.Errorf("正文应包含脚注: %q", msg.Body)
+	}
+}
+
+func TestParseCommitMessage_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"缺少主题":  `{"type":"fix","scope":"","subject":"  ","body":"","breaking":false,"footers":[]}`,
+		"无效的类型": `{"type":"bug fix","scope":"","subject":"x","body":"","breaking":false,"footers":[]}`,
+		"无效的范围": `{"type":"fix","scope":"a(b)","subject":"x","body":"","breaking":false,"footers":[]}`,
+		"格式错误":  `{"type":"fix","subject":}`,
+	}
+	for name, content := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseCommitMessage(content); err == nil {
+				t.Errorf("期望返回错误: %s", content)
+			}
+		})
+	}
+}
+
+func TestParseCommitMessage_PlainText(t *testing.T) {
+	msg, err := parseCommitMessage("fix(db)!: drop legacy column\n\nRemove the unused column.\n\nBREAKING CHANGE: column removed\nRefs: #42")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if msg.Title != "fix(db)!: drop legacy column" || msg.Type != "fix" || msg.Scope != "db" || msg.Subject != "drop legacy column" {
+		t.Errorf("标题解析错误: %+v", msg)
+	}
+	if !msg.Breaking || len(msg.Footers) != 2 {
+		t.Errorf("脚注解析错误: breaking=%v footers=%v", msg.Breaking, msg.Footers)
+	}
+
+	// 正文中的花括号不应被当作 JSON
+	msg, err = parseCommitMessage("refactor: simplify config\n\nUse map[string]struct{} for lookups")
+	if err != nil || msg.Type != "refactor" || msg.Footers != nil {
+		t.Errorf("纯文本解析错误: %+v, %v", msg, err)
+	}
+
+	msg, _ = parseCommitMessage("update stuff")
+	if msg.Type != "" || msg.Subject != "update stuff" {
+		t.Errorf("不符合约定格式的标题应只填写主题: %+v", msg)
+	}
+}
+
+func TestCommitMessageSchema(t *testing.T) {
+	data := marshalSchema(commitMessageSchema(commitStyle{language: "en"}))
+	if !strings.HasPrefix(string(data), `{"type":"object","properties":{"type":`) {
+		t.Errorf("字段应按声明顺序序列化: %s", data)
+	}
+	if !strings.Contains(string(data), `"additionalProperties":false`) {
+		t.Error("schema 应禁止额外字段")
+	}
+
+	gemini := string(marshalSchema(geminiSchema(commitMessageSchema(commitStyle{language: "en"}))))
+	if strings.Contains(gemini, "additionalProperties") || !strings.Contains(gemini, `"type":"OBJECT"`) || !strings.Contains(gemini, `"propertyOrdering"`) {
+		t.Errorf("Gemini schema 转换错误: %s", gemini)
+	}
+
+	var decoded jsonSchema
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Properties[2].Name != "subject" {
+		t.Errorf("反序列化应保持字段顺序: %v", err)
+	}
+}
+
+func TestStructuredStream(t *testing.T) {
+	var out strings.Builder
+	write := newStructuredStream(func(s string) { out.WriteString(s) })
+
+	response := `{"type":"feat","scope":"ui","subject":"add \"dark\" mode","body":"- toggle\n- 保存设置 ✓","breaking":false,"footers":["Refs #1"]}`
+	// 按很小的片段写入，模拟流式返回被任意切分
+	for i := 0; i < len(response); i += 3 {
+		write(response[i:min(i+3, len(response))])
+	}
+
+	want := "add \"dark\" mode\n\n- toggle\n- 保存设置 ✓"
+	if out.String() != want {
+		t.Errorf("期望输出 %q, 实际 %q", want, out.String())
+	}
+
+	// 不是 JSON 的回复原样转发
+	out.Reset()
+	write = newStructuredStream(func(s string) { out.WriteString(s) })
+	write("feat: plain ")
+	write("text")
+	if out.String() != "feat: plain text" {
+		t.Errorf("纯文本回复应原样转发, 实际 %q", out.String())
+	}
+
+	if newStructuredStream(nil) != nil {
+		t.Error("onToken 为 nil 时应返回 nil")
+	}
+}
+
+func TestOpenAIResponseFormat(t *testing.T) {
+	schema := commitMessageSchema(commitStyle{language: "en"})
+	testCases := []struct {
+		provider, baseURL, model string
+		want                     string
+	}{
+		{"openai", "", "gpt-4o-mini", "json_schema"},
+		{"openai", "https://api.openai.com/v1", "gpt-4.1", "json_schema"},
+		{"openai", "", "gpt-3.5-turbo", "json_object"},
+		{"openai", "", "gpt-4", ""},
+		{"openai", "https://api.deepseek.com", "deepseek-chat", ""},
+		{"azure", "https://example.openai.azure.com", "my-deployment", "json_object"},
+	}
+	for _, tc := range testCases {
+		p := &OpenAIProvider{provider: tc.provider, baseURL: tc.baseURL, model: tc.model}
+		got := ""
+		if format := p.responseFormat(schema); format != nil {
+			got = string(format.Type)
+		}
+		if got != tc.want {
+			t.Errorf("%s/%s: 期望 %q, 实际 %q", tc.provider, tc.model, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// DiffMode diff 超出预算时的处理方式
+type DiffMode string
+
+const (
+	// DiffModeTruncate 截断超出预算的部分（默认）
+	DiffModeTruncate DiffMode = "truncate"
+	// DiffModeSummarize 分块并发总结后再根据各块摘要生成提交消息
+	DiffModeSummarize DiffMode = "summarize"
+)
+
+// DefaultSummaryConcurrency 默认同时进行的分块总结请求数
+const DefaultSummaryConcurrency = 4
+
+// MaxSummaryChunks 分块总结的最大块数，每块都是一次计费的请求
+// 超出时（如提交了整个 vendor 目录）改为截断，避免一次生成发出大量请求
+const MaxSummaryChunks = 16
+
+// ParseDiffMode 解析 diff 处理方式，空字符串表示默认的 DiffModeTruncate
+func ParseDiffMode(s string) (DiffMode, error) {
+	switch DiffMode(s) {
+	case "", DiffModeTruncate:
+		return DiffModeTruncate, nil
+	case DiffModeSummarize:
+		return DiffModeSummarize, nil
+	default:
+		return "", fmt.Errorf("不支持的 diff 处理方式: %s（可选: truncate, summarize）", s)
+	}
+}
+
+// DiffPolicy 控制发送给模型的 diff 大小
+type DiffPolicy struct {
+	Mode DiffMode
+	// Budget 单次请求中 diff 的最大 token 数
+	Budget int
+	// Concurrency 分块总结时的最大并发请求数
+	Concurrency int
+	// Tokenizer 统计 token 数，为 nil 时按字符估算
+	Tokenizer Tokenizer
+}
+
+// diffPolicyFrom 根据配置生成 diff 策略，未配置预算时按模型的上下文窗口计算
+func diffPolicyFrom(cfg ProviderConfig) DiffPolicy {
+	return diffPolicyWithWindow(cfg, ContextWindow(cfg.Model))
+}
+
+// diffPolicyWithWindow 使用指定的上下文窗口生成 diff 策略
+func diffPolicyWithWindow(cfg ProviderConfig, window int) DiffPolicy {
+	policy := DiffPolicy{
+		Mode:        cfg.DiffMode,
+		Budget:      cfg.DiffBudget,
+		Concurrency: cfg.SummaryConcurrency,
+		Tokenizer:   TokenizerFor(cfg.Model),
+	}
+	if policy.Mode == "" {
+		policy.Mode = DiffModeTruncate
+	}
+	if policy.Budget <= 0 {
+		policy.Budget = diffBudgetFor(window)
+	}
+	if policy.Concurrency <= 0 {
+		policy.Concurrency = DefaultSummaryConcurrency
+	}
+	return policy
+}
+
+// count 统计文本的 token 数
+func (p DiffPolicy) count(text string) int {
+	if p.Tokenizer == nil {
+		return estimateTokens(text)
+	}
+	return p.Tokenizer.Count(text)
+}
+
+// byteBudget 按文本的平均每 token 字节数，把 token 预算换算为字节数
+func (p DiffPolicy) byteBudget(text string, tokens int) int {
+	if tokens <= 0 {
+		return len(text)
+	}
+	return int(int64(len(text)) * int64(p.Budget) / int64(tokens))
+}
+
+// truncate 把 diff 截断到 token 预算以内
+// 先按平均每 token 字节数换算，仍超出时逐步缩小
+func (p DiffPolicy) truncate(diff string) string {
+	tokens := p.count(diff)
+	if tokens <= p.Budget {
+		return diff
+	}
+
+	maxBytes := p.byteBudget(diff, tokens)
+	for {
+		truncated := truncateDiff(diff, maxBytes)
+		if maxBytes <= 200 || p.count(truncated) <= p.Budget {
+			return truncated
+		}
+		maxBytes = maxBytes * 9 / 10
+	}
+}
+
+// diffChunk 一次总结请求处理的 diff 片段
+type diffChunk struct {
+	Files   []string
+	Content string
+}
+
+// fileDiff 单个文件的 diff
+type fileDiff struct {
+	path    string
+	content string
+}
+
+// splitFileDiffs 按 "diff --git" 把 diff 切分为单个文件，第一个文件之前的内容会被丢弃
+func splitFileDiffs(diff string) []fileDiff {
+	const diffSeparator = "diff --git "
+
+	if !strings.HasPrefix(diff, diffSeparator) {
+		i := strings.Index(diff, "\n"+diffSeparator)
+		if i < 0 {
+			return nil
+		}
+		diff = diff[i+1:]
+	}
+
+	var files []fileDiff
+	for i, part := range strings.Split(diff, "\n"+diffSeparator) {
+		if i > 0 {
+			part = diffSeparator + part
+		}
+		if !strings.HasSuffix(part, "\n") {
+			part += "\n"
+		}
+
+		header, _, _ := strings.Cut(part, "\n")
+		filePath := strings.TrimPrefix(header, diffSeparator)
+		if j := strings.Index(filePath, " b/"); j >= 0 {
+			filePath = filePath[j+3:]
+		}
+		files = append(files, fileDiff{path: filePath, content: part})
+	}
+	return files
+}
+
+// splitDiff 把 diff 切分为不超过 budget 字节的若干块
+// 同一目录（包）的文件尽量放在同一块中，相邻的小包合并以减少请求数；
+// 包过大时按文件切分，单个文件超出预算时截断
+func splitDiff(diff string, budget int) []diffChunk {
+	files := splitFileDiffs(diff)
+
+	// 按目录分组，保持目录首次出现的顺序
+	var dirs []string
+	byDir := map[string][]fileDiff{}
+	for _, f := range files {
+		if len(f.content) > budget {
+			f.content = truncateFileDiff(f.content, budget)
+		}
+		dir := path.Dir(f.path)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], f)
+	}
+
+	var chunks []diffChunk
+	var current diffChunk
+	flush := func() {
+		if current.Content != "" {
+			chunks = append(chunks, current)
+			current = diffChunk{}
+		}
+	}
+
+	for _, dir := range dirs {
+		size := 0
+		for _, f := range byDir[dir] {
+			size += len(f.content)
+		}
+		// 放得下整个包时不把它拆到两块中
+		if size <= budget && len(current.Content)+size > budget {
+			flush()
+		}
+		for _, f := range byDir[dir] {
+			if len(current.Content)+len(f.content) > budget {
+				flush()
+			}
+			current.Files = append(current.Files, f.path)
+			current.Content += f.content
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// summarizeCommitMessage 分块总结超出预算的 diff，再根据摘要生成提交消息
+func summarizeCommitMessage(ctx context.Context, c chatClient, style commitStyle, info *CommitInfo, chunks []diffChunk, policy DiffPolicy, onToken func(string)) (*CommitMessage, error) {
+	summaries, usage, err := summarizeChunks(ctx, c, style.language, chunks, policy.Concurrency)
+	if err != nil {
+		return nil, fmt.Errorf("总结 diff 失败: %w", err)
+	}
+
+	var content strings.Builder
+	content.WriteString(summariesHeader(style.language))
+	for i, chunk := range chunks {
+		fmt.Fprintf(&content, "\n[%s]\n%s\n", strings.Join(chunk.Files, ", "), summaries[i])
+	}
+
+	summarizedInfo := &CommitInfo{
+		FilesChanged: info.FilesChanged,
+		DiffContent:  policy.truncate(content.String()),
+		BranchName:   info.BranchName,
+	}
+	message, err := requestCommitMessage(ctx, c, style, summarizedInfo, onToken)
+	if err != nil {
+		return nil, err
+	}
+	message.Usage = usage.add(message.Usage)
+	return message, nil
+}
+
+// summarizeChunks 使用最多 concurrency 个并发请求总结各块，结果与 chunks 一一对应
+// 任意一块失败时取消其余请求并返回该错误
+func summarizeChunks(ctx context.Context, c chatClient, language string, chunks []diffChunk, concurrency int) ([]string, Usage, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	summaries := make([]string, len(chunks))
+	usages := make([]Usage, len(chunks))
+	errs := make([]error, len(chunks))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(chunks)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// 已有请求失败时不再发送剩余的请求
+				if ctx.Err() != nil {
+					continue
+				}
+				resp, err := c.chat(ctx, &chatRequest{
+					System:      summarySystemPrompt(language),
+					Messages:    []chatMessage{{Role: roleUser, Content: chunks[i].Content}},
+					Temperature: 0.3,
+					MaxTokens:   300,
+				})
+				if err != nil {
+					errs[i] = err
+					cancel()
+					continue
+				}
+				summaries[i] = strings.TrimSpace(resp.Content)
+				usages[i] = resp.Usage
+			}
+		}()
+	}
+
+feed:
+	for i := range chunks {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// 优先返回导致取消的错误，而不是被取消的请求返回的 context.Canceled
+	var firstErr error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, context.Canceled) {
+			return nil, Usage{}, err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, Usage{}, firstErr
+	}
+
+	var total Usage
+	for _, u := range usages {
+		total = total.add(u)
+	}
+	return summaries, total, nil
+}
+
+// add 累加 token 用量
+func (u Usage) add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
@@ -0,0 +1,232 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fileDiffText 生成单个文件的 diff，lines 为变更行数
+func fileDiffText(path string, lines int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n@@ -1,1 +1,%d @@\n", path, path, path, path, lines)
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&b, "+line %d of %s\n", i, path)
+	}
+	return b.String()
+}
+
+func TestSplitFileDiffs(t *testing.T) {
+	diff := "warning: ignored preamble\n" + fileDiffText("a/x.go", 2) + fileDiffText("b/y.go", 2)
+	files := splitFileDiffs(diff)
+	if len(files) != 2 || files[0].path != "a/x.go" || files[1].path != "b/y.go" {
+		t.Fatalf("期望切分为 a/x.go 和 b/y.go, 实际=%+v", files)
+	}
+	if files[0].content+files[1].content != strings.TrimPrefix(diff, "warning: ignored preamble\n") {
+		t.Error("切分后的内容拼接起来应与原 diff 一致")
+	}
+
+	if files := splitFileDiffs("not a diff"); files != nil {
+		t.Errorf("没有 diff 头时应返回空, 实际=%+v", files)
+	}
+}
+
+func TestSplitDiff_GroupsByPackage(t *testing.T) {
+	diff := fileDiffText("a/x.go", 3) + fileDiffText("b/y.go", 3) + fileDiffText("a/z.go", 3)
+	size := len(fileDiffText("a/x.go", 3))
+
+	// 预算足够时合并为一块
+	if chunks := splitDiff(diff, len(diff)); len(chunks) != 1 {
+		t.Errorf("期望 1 块, 实际=%d", len(chunks))
+	}
+
+	// 预算只够两个文件时，同一个包的文件放在同一块
+	chunks := splitDiff(diff, 2*size)
+	if len(chunks) != 2 {
+		t.Fatalf("期望 2 块, 实际=%d", len(chunks))
+	}
+	if strings.Join(chunks[0].Files, ",") != "a/x.go,a/z.go" || strings.Join(chunks[1].Files, ",") != "b/y.go" {
+		t.Errorf("期望按包分组, 实际=%v / %v", chunks[0].Files, chunks[1].Files)
+	}
+}
+
+func TestSplitDiff_LargePackageSplitPerFile(t *testing.T) {
+	var diff strings.Builder
+	for i := 0; i < 6; i++ {
+		diff.WriteString(fileDiffText(fmt.Sprintf("pkg/f%d.go", i), 10))
+	}
+	budget := 2*len(fileDiffText("pkg/f0.go", 10)) + 10
+
+	chunks := splitDiff(diff.String(), budget)
+	if len(chunks) != 3 {
+		t.Fatalf("期望 3 块, 实际=%d", len(chunks))
+	}
+	total := 0
+	for _, c := range chunks {
+		if len(c.Content) > budget {
+			t.Errorf("块大小 %d 超出预算 %d", len(c.Content), budget)
+		}
+		total += len(c.Files)
+	}
+	if total != 6 {
+		t.Errorf("所有文件都应出现在某一块中, 实际=%d", total)
+	}
+}
+
+func TestSplitDiff_TruncatesLargeFile(t *testing.T) {
+	diff := fileDiffText("big.go", 500) + fileDiffText("small.go", 2)
+	budget := 1000
+
+	chunks := splitDiff(diff, budget)
+	if len(chunks) != 2 {
+		t.Fatalf("期望 2 块, 实际=%d", len(chunks))
+	}
+	if len(chunks[0].Content) > budget || !strings.Contains(chunks[0].Content, "truncated") {
+		t.Errorf("超出预算的文件应被截断, 长度=%d", len(chunks[0].Content))
+	}
+}
+
+// scriptedClient 模拟模型：总结请求返回固定摘要，生成请求返回提交消息，并记录最大并发数
+type scriptedClient struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	requests []*chatRequest
+	fail     string // 内容包含该字符串的总结请求返回错误
+}
+
+func (c *scriptedClient) chat(ctx context.Context, req *chatRequest) (*chatResponse, error) {
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	c.inFlight++
+	if c.inFlight > c.peak {
+		c.peak = c.inFlight
+	}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+
+	content := req.Messages[0].Content
+	if req.System != summarySystemPrompt("en") {
+		return &chatResponse{Content: "feat: final message", Usage: Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}}, nil
+	}
+	if c.fail != "" && strings.Contains(content, c.fail) {
+		return nil, errors.New("summary failed")
+	}
+
+	select {
+	case <-time.After(10 * time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	path := splitFileDiffs(content)[0].path
+	return &chatResponse{Content: "- changed " + path, Usage: Usage{PromptTokens: 5, CompletionTokens: 1, TotalTokens: 6}}, nil
+}
+
+func (c *scriptedClient) displayName() string { return "scripted" }
+
+func (c *scriptedClient) modelName() string { return "scripted-model" }
+
+func TestGenerateCommitMessage_Summarize(t *testing.T) {
+	var diff strings.Builder
+	for i := 0; i < 5; i++ {
+		diff.WriteString(fileDiffText(fmt.Sprintf("pkg%d/file.go", i), 20))
+	}
+	policy := DiffPolicy{Mode: DiffModeSummarize, Budget: estimateTokens(fileDiffText("pkg0/file.go", 20)), Concurrency: 2}
+
+	client := &scriptedClient{}
+	msg, err := generateCommitMessage(context.Background(), client, commitStyle{language: "en"}, &CommitInfo{DiffContent: diff.String()}, policy, nil)
+	if err != nil {
+		t.Fatalf("生成失败: %v", err)
+	}
+
+	if len(client.requests) != 6 {
+		t.Fatalf("期望 5 次总结 + 1 次生成, 实际=%d", len(client.requests))
+	}
+	if client.peak > 2 {
+		t.Errorf("并发数不应超过 2, 实际=%d", client.peak)
+	}
+	if msg.Title != "feat: final message" {
+		t.Errorf("期望 Title='feat: final message', 实际='%s'", msg.Title)
+	}
+	if msg.Usage.TotalTokens != 5*6+12 {
+		t.Errorf("Token 用量应包含总结请求, 实际=%d", msg.Usage.TotalTokens)
+	}
+
+	final := client.requests[len(client.requests)-1].Messages[0].Content
+	for i := 0; i < 5; i++ {
+		if !strings.Contains(final, fmt.Sprintf("- changed pkg%d/file.go", i)) {
+			t.Errorf("最终请求应包含 pkg%d 的摘要", i)
+		}
+	}
+	if strings.Contains(final, "+line 0") {
+		t.Error("最终请求不应包含原始 diff")
+	}
+}
+
+func TestGenerateCommitMessage_SummarizeError(t *testing.T) {
+	diff := fileDiffText("a/x.go", 20) + fileDiffText("b/y.go", 20) + fileDiffText("c/z.go", 20)
+	policy := DiffPolicy{Mode: DiffModeSummarize, Budget: estimateTokens(fileDiffText("a/x.go", 20)), Concurrency: 1}
+
+	client := &scriptedClient{fail: "b/y.go"}
+	_, err := generateCommitMessage(context.Background(), client, commitStyle{language: "en"}, &CommitInfo{DiffContent: diff}, policy, nil)
+	if err == nil || !strings.Contains(err.Error(), "summary failed") {
+		t.Fatalf("期望返回总结失败的错误, 实际=%v", err)
+	}
+	if len(client.requests) != 2 {
+		t.Errorf("失败后不应继续发送请求, 实际请求数=%d", len(client.requests))
+	}
+}
+
+func TestGenerateCommitMessage_TooManyChunks(t *testing.T) {
+	var diff strings.Builder
+	for i := 0; i <= MaxSummaryChunks; i++ {
+		diff.WriteString(fileDiffText(fmt.Sprintf("pkg%d/file.go", i), 20))
+	}
+	policy := DiffPolicy{Mode: DiffModeSummarize, Budget: estimateTokens(fileDiffText("pkg0/file.go", 20)), Concurrency: 2}
+
+	client := &scriptedClient{}
+	message, err := generateCommitMessage(context.Background(), client, commitStyle{language: "en"}, &CommitInfo{DiffContent: diff.String()}, policy, nil)
+	if err != nil {
+		t.Fatalf("生成失败: %v", err)
+	}
+	if len(client.requests) != 1 {
+		t.Errorf("超过 %d 块时应改为截断, 只发送 1 次请求, 实际=%d", MaxSummaryChunks, len(client.requests))
+	}
+	if want := fmt.Sprintf("diff 分为 %d 块", MaxSummaryChunks+1); len(message.Warnings) != 1 || !strings.Contains(message.Warnings[0], want) {
+		t.Errorf("改为截断时应提示块数, 实际=%v", message.Warnings)
+	}
+}
+
+func TestGenerateCommitMessage_TruncateMode(t *testing.T) {
+	diff := fileDiffText("a/x.go", 2000) + fileDiffText("b/y.go", 2000)
+	policy := diffPolicyFrom(ProviderConfig{})
+
+	client := &scriptedClient{}
+	_, err := generateCommitMessage(context.Background(), client, commitStyle{language: "en"}, &CommitInfo{DiffContent: diff}, policy, nil)
+	if err != nil {
+		t.Fatalf("生成失败: %v", err)
+	}
+	if len(client.requests) != 1 {
+		t.Fatalf("截断模式应只发送 1 次请求, 实际=%d", len(client.requests))
+	}
+	if tokens := estimateTokens(client.requests[0].Messages[0].Content); tokens > policy.Budget+200 {
+		t.Errorf("截断模式应按默认预算截断 diff, 实际 %d tokens, 预算 %d", tokens, policy.Budget)
+	}
+}
+
+func TestParseDiffMode(t *testing.T) {
+	if mode, err := ParseDiffMode(""); err != nil || mode != DiffModeTruncate {
+		t.Errorf("空值应为 truncate, 实际=%s, %v", mode, err)
+	}
+	if _, err := ParseDiffMode("map-reduce"); err == nil {
+		t.Error("未知的处理方式应返回错误")
+	}
+}
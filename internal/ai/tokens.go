@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tiktoken-go/tokenizer"
+)
+
+const (
+	// defaultContextWindow 未知模型（如 Azure 部署名）的上下文窗口，按较小的模型保守估计
+	defaultContextWindow = 8192
+	// reservedTokens 为系统提示、文件列表和生成的提交消息预留的 token 数
+	reservedTokens = 2048
+	// maxAutoDiffBudget 按上下文窗口自动计算的 diff 预算上限，避免大窗口模型每次提交消耗过多 token
+	maxAutoDiffBudget = 16000
+	// minDiffBudget diff 预算下限
+	minDiffBudget = 1024
+	// ollamaContextWindow Ollama 默认的 num_ctx，超出部分会被服务端静默丢弃
+	ollamaContextWindow = 4096
+)
+
+// contextWindows 常见模型的上下文窗口（token），按最长前缀匹配模型名称
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-5", 400000},
+	{"gpt-4.1", 1047576},
+	{"gpt-4o", 128000},
+	{"chatgpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"claude", 200000},
+	{"gemini-1.5-pro", 2097152},
+	{"gemini", 1048576},
+	{"llama3.1", 131072},
+	{"llama3.2", 131072},
+	{"llama3.3", 131072},
+	{"llama3", 8192},
+	{"qwen2.5", 32768},
+	{"qwen3", 40960},
+	{"mistral", 32768},
+	{"deepseek", 65536},
+}
+
+// ContextWindow 返回模型的上下文窗口大小，未知模型返回保守的默认值
+func ContextWindow(model string) int {
+	model = strings.ToLower(model)
+	best, window := 0, defaultContextWindow
+	for _, w := range contextWindows {
+		if len(w.prefix) > best && strings.HasPrefix(model, w.prefix) {
+			best, window = len(w.prefix), w.tokens
+		}
+	}
+	return window
+}
+
+// diffBudgetFor 根据上下文窗口计算 diff 的 token 预算
+func diffBudgetFor(window int) int {
+	return max(min(window-reservedTokens, maxAutoDiffBudget), minDiffBudget)
+}
+
+// Tokenizer 统计文本的 token 数
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// TokenizerFor 返回模型对应的分词器：OpenAI 模型使用 tiktoken 兼容的 BPE，其他模型按字符估算
+func TokenizerFor(model string) Tokenizer {
+	codec, err := tokenizer.ForModel(tokenizer.Model(strings.ToLower(model)))
+	if err != nil {
+		return heuristicTokenizer{}
+	}
+	return bpeTokenizer{codec: codec}
+}
+
+// bpeTokenizer 使用 BPE 词表精确计数
+type bpeTokenizer struct {
+	codec tokenizer.Codec
+}
+
+func (t bpeTokenizer) Count(text string) int {
+	n, err := t.codec.Count(text)
+	if err != nil {
+		return estimateTokens(text)
+	}
+	return n
+}
+
+// heuristicTokenizer 没有词表的模型按字符类型估算
+type heuristicTokenizer struct{}
+
+func (heuristicTokenizer) Count(text string) int {
+	return estimateTokens(text)
+}
+
+// estimateTokens 估算 token 数：中日韩字符约 1 个 token，其余约 4 个字节 1 个 token
+func estimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return cjk + (other+3)/4
+}
@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContextWindow(t *testing.T) {
+	tests := map[string]int{
+		"gpt-4o":                     128000,
+		"gpt-4o-mini":                128000,
+		"gpt-4":                      8192,
+		"gpt-4-turbo-2024-04-09":     128000,
+		"gpt-4.1-mini":               1047576,
+		"claude-sonnet-4-5-20250929": 200000,
+		"gemini-1.5-pro-latest":      2097152,
+		"gemini-2.5-flash":           1048576,
+		"llama3.1:8b":                131072,
+		"llama3:latest":              8192,
+		"my-azure-deployment":        defaultContextWindow,
+		"":                           defaultContextWindow,
+	}
+	for model, want := range tests {
+		if got := ContextWindow(model); got != want {
+			t.Errorf("ContextWindow(%q) = %d, 期望 %d", model, got, want)
+		}
+	}
+}
+
+func TestDiffBudgetFor(t *testing.T) {
+	if got := diffBudgetFor(8192); got != 8192-reservedTokens {
+		t.Errorf("8k 窗口期望预算 %d, 实际 %d", 8192-reservedTokens, got)
+	}
+	if got := diffBudgetFor(1000000); got != maxAutoDiffBudget {
+		t.Errorf("大窗口的预算应不超过 %d, 实际 %d", maxAutoDiffBudget, got)
+	}
+	if got := diffBudgetFor(2048); got != minDiffBudget {
+		t.Errorf("小窗口的预算应不低于 %d, 实际 %d", minDiffBudget, got)
+	}
+}
+
+func TestTokenizerFor(t *testing.T) {
+	if _, ok := TokenizerFor("gpt-4o-mini").(bpeTokenizer); !ok {
+		t.Error("OpenAI 模型应使用 BPE 分词器")
+	}
+	if _, ok := TokenizerFor("claude-3-5-haiku").(heuristicTokenizer); !ok {
+		t.Error("其他模型应使用估算")
+	}
+
+	if n := TokenizerFor("gpt-4o").Count("hello world"); n != 2 {
+		t.Errorf("期望 'hello world' 为 2 个 token, 实际 %d", n)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if n := estimateTokens("abcdefgh"); n != 2 {
+		t.Errorf("期望 8 个 ASCII 字符约为 2 个 token, 实际 %d", n)
+	}
+	// 中文每个字约 1 个 token，而不是按 UTF-8 字节数的 3/4
+	if n := estimateTokens("修复登录失败的问题"); n != 9 {
+		t.Errorf("期望 9 个汉字约为 9 个 token, 实际 %d", n)
+	}
+}
+
+func TestDiffPolicyTruncate_CJK(t *testing.T) {
+	var diff strings.Builder
+	diff.WriteString("diff --git a/docs/zh.md b/docs/zh.md\n--- a/docs/zh.md\n+++ b/docs/zh.md\n@@ -1,1 +1,500 @@\n")
+	for i := 0; i < 500; i++ {
+		diff.WriteString("+这是一行用于测试分词的中文文档内容，包含一些标点符号。\n")
+	}
+
+	policy := diffPolicyFrom(ProviderConfig{Model: "gpt-4o", DiffBudget: 1000})
+	truncated := policy.truncate(diff.String())
+	if n := policy.count(truncated); n > 1000 {
+		t.Errorf("截断后应不超过 1000 个 token, 实际 %d", n)
+	}
+	if !strings.Contains(truncated, "truncated") {
+		t.Error("截断后应包含截断提示")
+	}
+	// 按字节计算的旧预算会让中文 diff 远超 token 预算，这里确认按 token 截断后仍保留了足够的内容
+	if n := policy.count(truncated); n < 500 {
+		t.Errorf("截断过多, 只保留了 %d 个 token", n)
+	}
+}
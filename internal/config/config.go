@@ -1,61 +1,103 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SimonGino/aicommit/internal/ai"
+	"github.com/SimonGino/aicommit/internal/lint"
+	"github.com/SimonGino/aicommit/internal/scan"
+	"github.com/SimonGino/aicommit/internal/secret"
 )
 
 type Config struct {
-	APIKey          string `json:"api_key"`
+	APIKey          string `json:"api_key,omitempty"`  // 配置了密钥存储时不写入配置文件
 	BaseURL         string `json:"base_url,omitempty"` // 对于 OpenAI 是 base URL，对于 Azure 是完整的 endpoint URL
 	Model           string `json:"model,omitempty"`
 	Language        string `json:"language"`
-	Provider        string `json:"provider,omitempty"`          // "openai" or "azure"
+	Provider        string `json:"provider,omitempty"`          // 已注册的提供商名称，见 ai.Providers()
 	AzureAPIVersion string `json:"azure_api_version,omitempty"` // Azure API 版本，如 "2024-02-15-preview"
+	MaxRetries      int    `json:"max_retries"`                 // 限流或服务端错误时的最大重试次数，0 表示不重试
+	RetryBaseDelay  string `json:"retry_base_delay,omitempty"`  // 首次重试的等待时间，如 "1s"、"500ms"
+	// Fallbacks 主提供商调用失败时依次尝试的备用提供商
+	Fallbacks []ProviderEntry `json:"fallbacks,omitempty"`
+	// DiffMode diff 超出预算时的处理方式：truncate（默认）、summarize
+	DiffMode string `json:"diff_mode,omitempty"`
+	// DiffBudget 单次请求中 diff 的最大 token 数，0 表示按模型的上下文窗口计算
+	DiffBudget int `json:"diff_budget,omitempty"`
+	// SummaryConcurrency 分块总结时的最大并发请求数，0 表示使用默认值
+	SummaryConcurrency int `json:"summary_concurrency,omitempty"`
+	// SecretScan 发送 diff 前检测到密钥时的处理方式：redact（默认）、abort、off
+	SecretScan string `json:"secret_scan,omitempty"`
+	// SecretAllowlist 密钥检测白名单，"path:" 前缀为文件 glob，其余为匹配密钥的正则表达式
+	SecretAllowlist []string `json:"secret_allowlist,omitempty"`
+	// CommitTypes 允许的提交类型，生成和 lint 检查使用同一组类型，为空时使用 ai.CommitTypeNames()
+	CommitTypes []string `json:"commit_types,omitempty"`
+
+	profile  string            // 所属的配置档案名称
+	sources  map[string]Source // 各配置项的来源，未记录的为默认值
+	repoFile string            // 生效的仓库配置文件路径
+	envNames map[string]string // 来自环境变量的配置项及对应的变量名
+
+	keyBackend secret.Backend // API Key 实际所在的存储后端
 }
 
-func LoadConfig() *Config {
-	cfg := &Config{
+// ProviderEntry 一个命名的提供商配置
+type ProviderEntry struct {
+	Name            string `json:"name"`
+	Provider        string `json:"provider"`
+	APIKey          string `json:"api_key,omitempty"`
+	BaseURL         string `json:"base_url,omitempty"`
+	Model           string `json:"model,omitempty"`
+	AzureAPIVersion string `json:"azure_api_version,omitempty"`
+}
+
+// defaultConfig 返回默认配置
+func defaultConfig() *Config {
+	return &Config{
 		Model:           "gpt-4o",
 		Language:        "en",
 		Provider:        "openai",             // 默认使用 OpenAI
 		AzureAPIVersion: "2024-02-15-preview", // Azure 的默认 API 版本
+		MaxRetries:      ai.DefaultMaxRetries,
 	}
+}
 
-	configFile := cfg.ConfigFile()
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		return cfg
-	}
+// warningOutput 配置警告的输出位置，测试中替换
+var warningOutput io.Writer = os.Stderr
 
-	data, err := os.ReadFile(configFile)
+// LoadConfig 加载当前使用的配置档案，并合并当前仓库的 .aicommit.json 和环境变量
+// 仓库配置或环境变量无效时输出警告，只使用全局配置
+func LoadConfig() *Config {
+	cfg, err := LoadProfile("")
 	if err != nil {
-		return cfg
+		fmt.Fprintf(warningOutput, "⚠ %v，已忽略仓库配置和环境变量\n", err)
+		return readConfigFile().current()
 	}
-
-	if err := json.Unmarshal(data, cfg); err != nil {
-		return cfg
-	}
-
 	return cfg
 }
 
+// Save 保存配置到所属的档案，不影响其他档案
+// 包含仓库配置、环境变量等覆盖项的配置不能保存，修改配置请使用 OpenProfile
 func (c *Config) Save() error {
-	if err := os.MkdirAll(filepath.Dir(c.ConfigFile()), 0755); err != nil {
-		return fmt.Errorf("创建配置目录失败: %w", err)
+	if name, source, ok := c.overridden(); ok {
+		return fmt.Errorf("配置项 %s 来自%s，无法保存到全局配置", name, source.Label())
 	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
-	if err != nil {
-		return fmt.Errorf("序列化配置失败: %w", err)
+	if err := os.MkdirAll(filepath.Dir(c.ConfigFile()), 0700); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
 	}
 
-	if err := os.WriteFile(c.ConfigFile(), data, 0644); err != nil {
-		return fmt.Errorf("保存配置文件失败: %w", err)
+	file := readConfigFile()
+	if err := file.setProfile(c); err != nil {
+		return err
 	}
-
-	return nil
+	return file.save()
 }
 
 func (c *Config) UpdateAPIKey(apiKey string) error {
@@ -74,23 +116,35 @@ func (c *Config) UpdateModel(model string) error {
 }
 
 func (c *Config) UpdateLanguage(language string) error {
+	if err := validateLanguage(language); err != nil {
+		return err
+	}
+	c.Language = language
+
+	return c.Save()
+}
+
+// validateLanguage 校验输出语言
+func validateLanguage(language string) error {
 	switch language {
 	case "en", "zh-CN", "zh-TW":
-		c.Language = language
+		return nil
 	default:
 		return fmt.Errorf("不支持的语言: %s", language)
 	}
-
-	return c.Save()
 }
 
 func (c *Config) UpdateProvider(provider string) error {
-	switch provider {
-	case "openai", "azure":
-		c.Provider = provider
-	default:
-		return fmt.Errorf("不支持的提供商: %s", provider)
+	factory, ok := ai.Lookup(provider)
+	if !ok {
+		return fmt.Errorf("不支持的提供商: %s（可选: %s）", provider, strings.Join(ai.Providers(), ", "))
+	}
+
+	// 仍在使用旧提供商的默认模型时，切换为新提供商的默认模型
+	if current, ok := ai.Lookup(c.Provider); ok && c.Model == current.DefaultModel {
+		c.Model = factory.DefaultModel
 	}
+	c.Provider = provider
 
 	return c.Save()
 }
@@ -100,7 +154,223 @@ func (c *Config) UpdateAzureAPIVersion(version string) error {
 	return c.Save()
 }
 
+func (c *Config) UpdateMaxRetries(maxRetries int) error {
+	if maxRetries < 0 {
+		return fmt.Errorf("重试次数不能为负数: %d", maxRetries)
+	}
+	c.MaxRetries = maxRetries
+	return c.Save()
+}
+
+func (c *Config) UpdateRetryBaseDelay(delay string) error {
+	d, err := time.ParseDuration(delay)
+	if err != nil {
+		return fmt.Errorf("无效的重试等待时间: %s（示例: 1s、500ms）", delay)
+	}
+	if d <= 0 {
+		return fmt.Errorf("重试等待时间必须大于 0: %s", delay)
+	}
+	c.RetryBaseDelay = delay
+	return c.Save()
+}
+
+// retryBaseDelay 解析重试等待时间，未配置或无效时返回 0（使用默认值）
+func (c *Config) retryBaseDelay() time.Duration {
+	d, err := time.ParseDuration(c.RetryBaseDelay)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+func (c *Config) UpdateDiffMode(mode string) error {
+	if _, err := ai.ParseDiffMode(mode); err != nil {
+		return err
+	}
+	c.DiffMode = mode
+	return c.Save()
+}
+
+func (c *Config) UpdateDiffBudget(budget int) error {
+	if budget < 0 {
+		return fmt.Errorf("diff 预算不能为负数: %d", budget)
+	}
+	c.DiffBudget = budget
+	return c.Save()
+}
+
+func (c *Config) UpdateSummaryConcurrency(concurrency int) error {
+	if concurrency < 0 {
+		return fmt.Errorf("并发数不能为负数: %d", concurrency)
+	}
+	c.SummaryConcurrency = concurrency
+	return c.Save()
+}
+
+func (c *Config) UpdateSecretScan(mode string) error {
+	if _, err := scan.ParseMode(mode); err != nil {
+		return err
+	}
+	c.SecretScan = mode
+	return c.Save()
+}
+
+// AddSecretAllow 添加密钥检测白名单条目
+func (c *Config) AddSecretAllow(entry string) error {
+	if _, err := scan.New(scan.ParseAllowlist([]string{entry})); err != nil {
+		return err
+	}
+	for _, e := range c.SecretAllowlist {
+		if e == entry {
+			return nil
+		}
+	}
+	c.SecretAllowlist = append(c.SecretAllowlist, entry)
+	return c.Save()
+}
+
+// RemoveSecretAllow 删除密钥检测白名单条目
+func (c *Config) RemoveSecretAllow(entry string) error {
+	for i, e := range c.SecretAllowlist {
+		if e == entry {
+			c.SecretAllowlist = append(c.SecretAllowlist[:i], c.SecretAllowlist[i+1:]...)
+			return c.Save()
+		}
+	}
+	return fmt.Errorf("白名单中没有: %s", entry)
+}
+
+// SecretScanner 根据配置创建密钥检测器，返回生效的处理方式
+func (c *Config) SecretScanner() (*scan.Scanner, scan.Mode, error) {
+	mode, err := scan.ParseMode(c.SecretScan)
+	if err != nil {
+		return nil, "", err
+	}
+	scanner, err := scan.New(scan.ParseAllowlist(c.SecretAllowlist))
+	if err != nil {
+		return nil, "", err
+	}
+	return scanner, mode, nil
+}
+
+func (c *Config) UpdateCommitTypes(types []string) error {
+	if err := validateCommitTypes(types); err != nil {
+		return err
+	}
+	c.CommitTypes = types
+	return c.Save()
+}
+
+// validateCommitTypes 校验提交类型
+func validateCommitTypes(types []string) error {
+	for i, t := range types {
+		if !lint.ValidType(t) {
+			return fmt.Errorf("无效的提交类型: %q（只能包含小写字母）", t)
+		}
+		if slices.Contains(types[:i], t) {
+			return fmt.Errorf("重复的提交类型: %s", t)
+		}
+	}
+	return nil
+}
+
+// CommitTypeNames 返回生效的提交类型，未配置时使用默认类型
+func (c *Config) CommitTypeNames() []string {
+	if len(c.CommitTypes) == 0 {
+		return ai.CommitTypeNames()
+	}
+	return c.CommitTypes
+}
+
+// LintRules 返回 lint 检查使用的规则，提交类型与生成时要求模型使用的类型一致
+func (c *Config) LintRules() lint.Rules {
+	return lint.DefaultRules(c.CommitTypeNames())
+}
+
+// Fallback 按名称查找备用提供商
+func (c *Config) Fallback(name string) (ProviderEntry, bool) {
+	for _, entry := range c.Fallbacks {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+	return ProviderEntry{}, false
+}
+
+// SetFallback 添加或更新备用提供商，新条目追加到故障转移链末尾
+func (c *Config) SetFallback(entry ProviderEntry) error {
+	if entry.Name == "" {
+		return fmt.Errorf("备用提供商名称不能为空")
+	}
+	if _, ok := ai.Lookup(entry.Provider); !ok {
+		return fmt.Errorf("不支持的提供商: %s（可选: %s）", entry.Provider, strings.Join(ai.Providers(), ", "))
+	}
+
+	for i := range c.Fallbacks {
+		if c.Fallbacks[i].Name == entry.Name {
+			c.Fallbacks[i] = entry
+			return c.Save()
+		}
+	}
+	c.Fallbacks = append(c.Fallbacks, entry)
+	return c.Save()
+}
+
+// RemoveFallback 删除备用提供商
+func (c *Config) RemoveFallback(name string) error {
+	for i, entry := range c.Fallbacks {
+		if entry.Name == name {
+			c.Fallbacks = append(c.Fallbacks[:i], c.Fallbacks[i+1:]...)
+			if err := c.Save(); err != nil {
+				return err
+			}
+			return deleteSecret(secretAccount(c.Profile(), name))
+		}
+	}
+	return fmt.Errorf("未找到备用提供商: %s", name)
+}
+
+// Entries 返回故障转移链：主提供商（以提供商名称命名）在前，备用提供商依次在后
+func (c *Config) Entries() []ProviderEntry {
+	primary := ProviderEntry{
+		Name:            c.Provider,
+		Provider:        c.Provider,
+		APIKey:          c.APIKey,
+		BaseURL:         c.BaseURL,
+		Model:           c.Model,
+		AzureAPIVersion: c.AzureAPIVersion,
+	}
+	return append([]ProviderEntry{primary}, c.Fallbacks...)
+}
+
+// ProviderConfig 转换为创建主提供商所需的配置
+func (c *Config) ProviderConfig(language string) ai.ProviderConfig {
+	return c.EntryConfig(c.Entries()[0], language)
+}
+
+// EntryConfig 转换为创建指定提供商所需的配置，重试、diff 策略和提交类型对所有提供商生效
+func (c *Config) EntryConfig(entry ProviderEntry, language string) ai.ProviderConfig {
+	return ai.ProviderConfig{
+		APIKey:             entry.APIKey,
+		BaseURL:            entry.BaseURL,
+		Model:              entry.Model,
+		Language:           language,
+		AzureAPIVersion:    entry.AzureAPIVersion,
+		MaxRetries:         c.MaxRetries,
+		RetryBaseDelay:     c.retryBaseDelay(),
+		DiffMode:           ai.DiffMode(c.DiffMode),
+		DiffBudget:         c.DiffBudget,
+		SummaryConcurrency: c.SummaryConcurrency,
+		CommitTypes:        c.CommitTypes,
+	}
+}
+
 func (c *Config) ConfigFile() string {
+	return configFilePath()
+}
+
+// configFilePath 返回全局配置文件路径
+func configFilePath() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		homeDir = "."
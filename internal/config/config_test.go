@@ -3,7 +3,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/SimonGino/aicommit/internal/ai"
+	"github.com/SimonGino/aicommit/internal/secret"
 )
 
 // setupTestConfig 创建测试用的临时配置目录
@@ -22,7 +27,19 @@ func setupTestConfig(t *testing.T) (string, func()) {
 	os.Setenv("HOME", tmpDir)
 	os.Setenv("USERPROFILE", tmpDir)
 
+	// 清除可能影响配置的环境变量
+	for _, name := range []string{
+		"AICOMMIT_PROVIDER", "AICOMMIT_API_KEY", "AICOMMIT_BASE_URL", "AICOMMIT_MODEL", "AICOMMIT_LANGUAGE", "AICOMMIT_SECRET_SCAN",
+		"OPENAI_API_KEY", "AZURE_OPENAI_API_KEY", "ANTHROPIC_API_KEY", "GEMINI_API_KEY",
+	} {
+		t.Setenv(name, "")
+	}
+
+	// 默认明文保存，避免测试访问系统钥匙串
+	detectSecretBackend = func() secret.Backend { return secret.BackendPlain }
+
 	cleanup := func() {
+		detectSecretBackend = secret.Detect
 		os.Setenv("HOME", originalHome)
 		os.Setenv("USERPROFILE", originalUserProfile)
 		os.RemoveAll(tmpDir)
@@ -117,7 +134,7 @@ func TestUpdateProvider_Valid(t *testing.T) {
 
 	cfg := LoadConfig()
 
-	testCases := []string{"openai", "azure"}
+	testCases := []string{"openai", "azure", "anthropic", "ollama", "gemini"}
 	for _, provider := range testCases {
 		err := cfg.UpdateProvider(provider)
 		if err != nil {
@@ -129,6 +146,30 @@ func TestUpdateProvider_Valid(t *testing.T) {
 	}
 }
 
+func TestUpdateProvider_SwitchDefaultModel(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	cfg := LoadConfig()
+
+	// 使用默认模型时，切换提供商应同步切换默认模型
+	if err := cfg.UpdateProvider("anthropic"); err != nil {
+		t.Fatalf("更新提供商失败: %v", err)
+	}
+	if cfg.Model != "claude-sonnet-4-5" {
+		t.Errorf("期望 Model='claude-sonnet-4-5', 实际='%s'", cfg.Model)
+	}
+
+	// 自定义模型不应被覆盖
+	cfg.Model = "my-model"
+	if err := cfg.UpdateProvider("openai"); err != nil {
+		t.Fatalf("更新提供商失败: %v", err)
+	}
+	if cfg.Model != "my-model" {
+		t.Errorf("期望 Model='my-model', 实际='%s'", cfg.Model)
+	}
+}
+
 func TestUpdateProvider_Invalid(t *testing.T) {
 	_, cleanup := setupTestConfig(t)
 	defer cleanup()
@@ -202,3 +243,147 @@ func TestUpdateAzureAPIVersion(t *testing.T) {
 		t.Errorf("期望 AzureAPIVersion='%s', 实际='%s'", testVersion, cfg.AzureAPIVersion)
 	}
 }
+
+func TestUpdateMaxRetries(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	cfg := LoadConfig()
+	if cfg.MaxRetries != 3 {
+		t.Errorf("期望默认 MaxRetries=3, 实际=%d", cfg.MaxRetries)
+	}
+
+	if err := cfg.UpdateMaxRetries(0); err != nil {
+		t.Errorf("更新 MaxRetries 失败: %v", err)
+	}
+	if reloaded := LoadConfig(); reloaded.MaxRetries != 0 {
+		t.Errorf("重新加载后期望 MaxRetries=0, 实际=%d", reloaded.MaxRetries)
+	}
+
+	if err := cfg.UpdateMaxRetries(-1); err == nil {
+		t.Error("期望负数重试次数返回错误")
+	}
+}
+
+func TestUpdateRetryBaseDelay(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	cfg := LoadConfig()
+
+	if err := cfg.UpdateRetryBaseDelay("500ms"); err != nil {
+		t.Errorf("更新 RetryBaseDelay 失败: %v", err)
+	}
+	if got := cfg.ProviderConfig("en").RetryBaseDelay; got != 500*time.Millisecond {
+		t.Errorf("期望 RetryBaseDelay=500ms, 实际=%v", got)
+	}
+
+	for _, invalid := range []string{"soon", "0s", "-1s"} {
+		if err := cfg.UpdateRetryBaseDelay(invalid); err == nil {
+			t.Errorf("期望无效的等待时间 %q 返回错误", invalid)
+		}
+	}
+}
+
+func TestFallbacks(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	cfg := LoadConfig()
+	cfg.Provider = "azure"
+
+	if err := cfg.SetFallback(ProviderEntry{Name: "openai", Provider: "openai", APIKey: "sk-1"}); err != nil {
+		t.Fatalf("添加备用提供商失败: %v", err)
+	}
+	if err := cfg.SetFallback(ProviderEntry{Name: "local", Provider: "ollama"}); err != nil {
+		t.Fatalf("添加备用提供商失败: %v", err)
+	}
+	// 更新已有条目不改变顺序
+	if err := cfg.SetFallback(ProviderEntry{Name: "openai", Provider: "openai", APIKey: "sk-2"}); err != nil {
+		t.Fatalf("更新备用提供商失败: %v", err)
+	}
+
+	reloaded := LoadConfig()
+	entries := reloaded.Entries()
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, ","); got != "azure,openai,local" {
+		t.Errorf("期望顺序 azure,openai,local, 实际=%s", got)
+	}
+	if entries[1].APIKey != "sk-2" {
+		t.Errorf("期望 openai 的 APIKey='sk-2', 实际='%s'", entries[1].APIKey)
+	}
+
+	if err := cfg.SetFallback(ProviderEntry{Name: "bad", Provider: "invalid"}); err == nil {
+		t.Error("期望无效提供商返回错误")
+	}
+
+	if err := cfg.RemoveFallback("openai"); err != nil {
+		t.Errorf("删除备用提供商失败: %v", err)
+	}
+	if _, ok := cfg.Fallback("openai"); ok {
+		t.Error("删除后不应再找到 openai")
+	}
+	if err := cfg.RemoveFallback("missing"); err == nil {
+		t.Error("期望删除不存在的条目返回错误")
+	}
+}
+
+func TestSecretScanSettings(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	cfg := LoadConfig()
+	if err := cfg.UpdateSecretScan("warn"); err == nil {
+		t.Error("期望不支持的模式返回错误")
+	}
+	if err := cfg.UpdateSecretScan("off"); err != nil {
+		t.Fatalf("配置检测模式失败: %v", err)
+	}
+	if err := cfg.AddSecretAllow("path:*.pem"); err != nil {
+		t.Fatalf("添加白名单失败: %v", err)
+	}
+	if err := cfg.AddSecretAllow("["); err == nil {
+		t.Error("期望无效的正则返回错误")
+	}
+
+	loaded := LoadConfig()
+	if loaded.SecretScan != "off" || len(loaded.SecretAllowlist) != 1 {
+		t.Errorf("配置未保存: SecretScan=%s, SecretAllowlist=%v", loaded.SecretScan, loaded.SecretAllowlist)
+	}
+	if err := loaded.RemoveSecretAllow("path:*.pem"); err != nil {
+		t.Fatalf("删除白名单失败: %v", err)
+	}
+	if err := loaded.RemoveSecretAllow("path:*.pem"); err == nil {
+		t.Error("期望删除不存在的条目返回错误")
+	}
+}
+
+func TestDiffSettings(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	cfg := LoadConfig()
+	if err := cfg.UpdateDiffMode("chunked"); err == nil {
+		t.Error("期望不支持的处理方式返回错误")
+	}
+	if err := cfg.UpdateDiffBudget(-1); err == nil {
+		t.Error("期望负数预算返回错误")
+	}
+	if err := cfg.UpdateDiffMode("summarize"); err != nil {
+		t.Fatalf("配置 diff 处理方式失败: %v", err)
+	}
+	if err := cfg.UpdateDiffBudget(20000); err != nil {
+		t.Fatalf("配置 diff 预算失败: %v", err)
+	}
+	if err := cfg.UpdateSummaryConcurrency(8); err != nil {
+		t.Fatalf("配置并发数失败: %v", err)
+	}
+
+	pc := LoadConfig().ProviderConfig("en")
+	if pc.DiffMode != ai.DiffModeSummarize || pc.DiffBudget != 20000 || pc.SummaryConcurrency != 8 {
+		t.Errorf("diff 策略未传递给提供商: %+v", pc)
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/SimonGino/aicommit/internal/ai"
+)
+
+// envVars 环境变量与配置项的对应关系，便于在 CI 和容器中免配置文件使用
+var envVars = []struct {
+	name  string
+	field string
+}{
+	{"AICOMMIT_PROVIDER", "provider"},
+	{"AICOMMIT_API_KEY", "api_key"},
+	{"AICOMMIT_BASE_URL", "base_url"},
+	{"AICOMMIT_MODEL", "model"},
+	{"AICOMMIT_LANGUAGE", "language"},
+	{"AICOMMIT_SECRET_SCAN", "secret_scan"},
+}
+
+// applyEnv 用环境变量覆盖配置
+func (c *Config) applyEnv() error {
+	for _, v := range envVars {
+		value := os.Getenv(v.name)
+		if value == "" {
+			continue
+		}
+		if err := c.Override(v.field, value, SourceEnv); err != nil {
+			return fmt.Errorf("环境变量 %s 无效: %w", v.name, err)
+		}
+		c.setEnvName(v.field, v.name)
+	}
+	return nil
+}
+
+// applyKeyEnv 仍未配置 API Key 时，读取提供商的常用环境变量（如 OPENAI_API_KEY、AZURE_OPENAI_API_KEY）
+// 返回是否从常用环境变量读到了 API Key
+func (c *Config) applyKeyEnv() bool {
+	if c.APIKey != "" {
+		return false
+	}
+	factory, ok := ai.Lookup(c.Provider)
+	if !ok || factory.APIKeyEnv == "" {
+		return false
+	}
+	value := os.Getenv(factory.APIKeyEnv)
+	if value == "" {
+		return false
+	}
+	c.APIKey = value
+	c.setSource("api_key", SourceEnv)
+	c.setEnvName("api_key", factory.APIKeyEnv)
+	return true
+}
+
+func (c *Config) setEnvName(field, name string) {
+	if c.envNames == nil {
+		c.envNames = map[string]string{}
+	}
+	c.envNames[field] = name
+}
+
+// adjustDefaultModel 提供商被更高优先级的来源覆盖，而模型仍是原提供商的默认模型时，
+// 改用新提供商的默认模型，避免把 gpt-4o 发给其他提供商
+func (c *Config) adjustDefaultModel(baseProvider string) {
+	if c.Provider == baseProvider || c.Source("model").rank() >= c.Source("provider").rank() {
+		return
+	}
+
+	base, ok := ai.Lookup(baseProvider)
+	if !ok || c.Model != base.DefaultModel {
+		return
+	}
+	if current, ok := ai.Lookup(c.Provider); ok {
+		c.Model = current.DefaultModel
+		c.setSource("model", c.Source("provider"))
+	}
+}
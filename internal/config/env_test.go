@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEnv_OverridesFileAndRepo(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	global := LoadConfig()
+	global.APIKey = "sk-from-file"
+	if err := global.Save(); err != nil {
+		t.Fatalf("保存全局配置失败: %v", err)
+	}
+
+	t.Setenv("AICOMMIT_API_KEY", "sk-from-env")
+	t.Setenv("AICOMMIT_LANGUAGE", "zh-TW")
+
+	dir := setupTestRepo(t, `{"language": "zh-CN"}`)
+	cfg, err := loadProfile("", dir)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.APIKey != "sk-from-env" || cfg.Language != "zh-TW" {
+		t.Errorf("环境变量应优先于配置文件: APIKey=%s, Language=%s", cfg.APIKey, cfg.Language)
+	}
+	if cfg.Source("language") != SourceEnv {
+		t.Errorf("期望 language 来源为 env, 实际=%s", cfg.Source("language"))
+	}
+}
+
+func TestEnv_ConventionalAPIKey(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	t.Setenv("AZURE_OPENAI_API_KEY", "azure-key")
+
+	cfg, err := loadProfile("", t.TempDir())
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.APIKey != "sk-openai" {
+		t.Errorf("期望读取 OPENAI_API_KEY, 实际='%s'", cfg.APIKey)
+	}
+
+	t.Setenv("AICOMMIT_PROVIDER", "azure")
+	cfg, err = loadProfile("", t.TempDir())
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.APIKey != "azure-key" {
+		t.Errorf("期望读取 AZURE_OPENAI_API_KEY, 实际='%s'", cfg.APIKey)
+	}
+	for _, f := range cfg.Fields() {
+		if f.Name == "api_key" && f.Env != "AZURE_OPENAI_API_KEY" {
+			t.Errorf("期望记录变量名 AZURE_OPENAI_API_KEY, 实际='%s'", f.Env)
+		}
+	}
+
+	// 配置文件中的密钥优先于常用环境变量
+	global, _ := OpenProfile("")
+	if err := global.UpdateAPIKey("sk-file"); err != nil {
+		t.Fatalf("保存全局配置失败: %v", err)
+	}
+	t.Setenv("AICOMMIT_PROVIDER", "")
+	cfg, _ = loadProfile("", t.TempDir())
+	if cfg.APIKey != "sk-file" {
+		t.Errorf("期望使用配置文件中的密钥, 实际='%s'", cfg.APIKey)
+	}
+}
+
+func TestEnv_ProviderSwitchesDefaultModel(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	t.Setenv("AICOMMIT_PROVIDER", "ollama")
+	cfg, err := loadProfile("", t.TempDir())
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.Model != "llama3.1" {
+		t.Errorf("期望切换为 Ollama 默认模型, 实际='%s'", cfg.Model)
+	}
+
+	t.Setenv("AICOMMIT_MODEL", "qwen2.5")
+	cfg, _ = loadProfile("", t.TempDir())
+	if cfg.Model != "qwen2.5" {
+		t.Errorf("显式指定的模型不应被替换, 实际='%s'", cfg.Model)
+	}
+}
+
+func TestEnv_InvalidLanguage(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	t.Setenv("AICOMMIT_LANGUAGE", "fr")
+	if _, err := loadProfile("", t.TempDir()); err == nil {
+		t.Error("期望不支持的语言返回错误")
+	}
+}
+
+func TestLoadConfig_InvalidEnvWarns(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	global := LoadConfig()
+	global.Language = "zh-TW"
+	if err := global.Save(); err != nil {
+		t.Fatalf("保存全局配置失败: %v", err)
+	}
+
+	var warnings strings.Builder
+	warningOutput = &warnings
+	defer func() { warningOutput = os.Stderr }()
+
+	t.Setenv("AICOMMIT_LANGUAGE", "fr")
+	cfg := LoadConfig()
+	if cfg.Language != "zh-TW" {
+		t.Errorf("环境变量无效时应使用全局配置, 实际 Language=%s", cfg.Language)
+	}
+	if !strings.Contains(warnings.String(), "AICOMMIT_LANGUAGE") {
+		t.Errorf("期望输出环境变量无效的警告, 实际='%s'", warnings.String())
+	}
+}
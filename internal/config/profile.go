@@ -0,0 +1,218 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile 默认档案名称，对应配置文件顶层的字段
+const DefaultProfile = "default"
+
+// configFile 配置文件的完整结构
+// 顶层字段为默认档案，profiles 中保存命名档案，current_profile 为默认使用的档案
+type configFile struct {
+	Config
+	CurrentProfile string                     `json:"current_profile,omitempty"`
+	SecretBackend  string                     `json:"secret_backend,omitempty"` // API Key 的存储后端，见 secret.Backend
+	Profiles       map[string]json.RawMessage `json:"profiles,omitempty"`
+
+	raw []byte // 原始文件内容，用于判断默认档案中设置了哪些字段
+}
+
+// readConfigFile 读取配置文件，文件不存在或格式错误时返回默认配置
+func readConfigFile() *configFile {
+	file := &configFile{Config: *defaultConfig()}
+
+	data, err := os.ReadFile(configFilePath())
+	if err != nil {
+		return file
+	}
+	if err := json.Unmarshal(data, file); err != nil {
+		return &configFile{Config: *defaultConfig()}
+	}
+	file.raw = data
+	return file
+}
+
+// current 返回当前使用的档案，档案不存在时返回默认档案
+func (f *configFile) current() *Config {
+	if cfg, ok := f.lookup(f.CurrentProfile); ok {
+		return cfg
+	}
+	cfg, _ := f.lookup(DefaultProfile)
+	return cfg
+}
+
+// save 写入配置文件，明文 API Key 会先移入密钥存储
+// 配置文件仅当前用户可读写
+func (f *configFile) save() error {
+	if err := f.storeSecrets(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+
+	if err := os.WriteFile(configFilePath(), data, 0600); err != nil {
+		return fmt.Errorf("保存配置文件失败: %w", err)
+	}
+	// WriteFile 不会修改已存在文件的权限
+	if err := os.Chmod(configFilePath(), 0600); err != nil {
+		return fmt.Errorf("设置配置文件权限失败: %w", err)
+	}
+	return nil
+}
+
+// lookup 返回指定档案的配置，name 为空表示默认档案
+// 命名档案中未设置的字段使用默认值
+func (f *configFile) lookup(name string) (*Config, bool) {
+	if name == "" || name == DefaultProfile {
+		cfg := f.Config
+		cfg.profile = DefaultProfile
+		cfg.markGlobal(f.raw)
+		return &cfg, true
+	}
+
+	raw, ok := f.Profiles[name]
+	if !ok {
+		return nil, false
+	}
+	cfg := defaultConfig()
+	if err := json.Unmarshal(raw, cfg); err != nil {
+		return nil, false
+	}
+	cfg.profile = name
+	cfg.markGlobal(raw)
+	return cfg, true
+}
+
+// setProfile 用 cfg 覆盖其所属档案
+func (f *configFile) setProfile(cfg *Config) error {
+	if cfg.profile == "" || cfg.profile == DefaultProfile {
+		f.Config = *cfg
+		return nil
+	}
+
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	if f.Profiles == nil {
+		f.Profiles = map[string]json.RawMessage{}
+	}
+	f.Profiles[cfg.profile] = raw
+	return nil
+}
+
+// names 返回所有档案名称，默认档案在前，其余按字母排序
+func (f *configFile) names() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...)
+}
+
+// LoadProfile 加载指定档案，依次合并当前仓库的 .aicommit.json 和环境变量，name 为空时加载当前使用的档案
+func LoadProfile(name string) (*Config, error) {
+	return loadProfile(name, "")
+}
+
+// loadProfile 加载指定档案，合并 dir 所在仓库的配置和环境变量
+func loadProfile(name, dir string) (*Config, error) {
+	file := readConfigFile()
+	file.migrateSecrets()
+
+	cfg := file.current()
+	if name != "" {
+		var ok bool
+		if cfg, ok = file.lookup(name); !ok {
+			return nil, fmt.Errorf("配置档案 %s 不存在（可选: %s），可使用 'aicommit config --profile %s ...' 创建",
+				name, strings.Join(file.names(), ", "), name)
+		}
+	}
+
+	baseProvider := cfg.Provider
+	if err := cfg.applyRepoConfig(dir); err != nil {
+		return nil, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	// 存储的密钥优先于提供商的常用环境变量，但密钥存储无法读取时（如没有终端输入口令）可以改用常用环境变量
+	if err := file.loadSecrets(cfg); err != nil && !cfg.applyKeyEnv() {
+		return nil, err
+	}
+	cfg.applyKeyEnv()
+	cfg.adjustDefaultModel(baseProvider)
+	return cfg, nil
+}
+
+// OpenProfile 打开指定档案用于修改（不合并仓库配置），name 为空时打开当前使用的档案
+// 档案不存在时以默认值新建，保存后生效
+func OpenProfile(name string) (*Config, error) {
+	file := readConfigFile()
+	if name == "" {
+		return file.current(), nil
+	}
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+
+	if cfg, ok := file.lookup(name); ok {
+		return cfg, nil
+	}
+	cfg := defaultConfig()
+	cfg.profile = name
+	return cfg, nil
+}
+
+// UseProfile 设置默认使用的档案
+func UseProfile(name string) error {
+	file := readConfigFile()
+	if _, ok := file.lookup(name); !ok {
+		return fmt.Errorf("配置档案 %s 不存在（可选: %s）", name, strings.Join(file.names(), ", "))
+	}
+
+	if name == DefaultProfile {
+		file.CurrentProfile = ""
+	} else {
+		file.CurrentProfile = name
+	}
+	return file.save()
+}
+
+// ListProfiles 返回所有档案名称及当前使用的档案
+func ListProfiles() (names []string, current string) {
+	file := readConfigFile()
+	current = file.CurrentProfile
+	if _, ok := file.lookup(current); !ok || current == "" {
+		current = DefaultProfile
+	}
+	return file.names(), current
+}
+
+// Profile 返回配置所属的档案名称
+func (c *Config) Profile() string {
+	if c.profile == "" {
+		return DefaultProfile
+	}
+	return c.profile
+}
+
+// validateProfileName 校验档案名称
+func validateProfileName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("配置档案名称不能为空")
+	}
+	if strings.ContainsAny(name, " \t\n/\\") {
+		return fmt.Errorf("配置档案名称不能包含空白或路径分隔符: %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfile_SaveKeepsOtherProfiles(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	if err := LoadConfig().UpdateAPIKey("sk-personal"); err != nil {
+		t.Fatalf("更新默认档案失败: %v", err)
+	}
+
+	work, err := OpenProfile("work")
+	if err != nil {
+		t.Fatalf("打开档案失败: %v", err)
+	}
+	if err := work.UpdateProvider("azure"); err != nil {
+		t.Fatalf("更新档案失败: %v", err)
+	}
+	if err := work.UpdateAPIKey("azure-key"); err != nil {
+		t.Fatalf("更新档案失败: %v", err)
+	}
+
+	if cfg := LoadConfig(); cfg.APIKey != "sk-personal" || cfg.Provider != "openai" {
+		t.Errorf("默认档案不应被修改, 实际 APIKey='%s', Provider='%s'", cfg.APIKey, cfg.Provider)
+	}
+
+	loaded, err := LoadProfile("work")
+	if err != nil {
+		t.Fatalf("加载档案失败: %v", err)
+	}
+	if loaded.APIKey != "azure-key" || loaded.Provider != "azure" || loaded.Profile() != "work" {
+		t.Errorf("档案内容不正确: %+v", loaded)
+	}
+}
+
+func TestProfile_UseProfile(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	offline, _ := OpenProfile("offline")
+	if err := offline.UpdateProvider("ollama"); err != nil {
+		t.Fatalf("更新档案失败: %v", err)
+	}
+
+	if err := UseProfile("offline"); err != nil {
+		t.Fatalf("切换档案失败: %v", err)
+	}
+	if cfg := LoadConfig(); cfg.Provider != "ollama" || cfg.Profile() != "offline" {
+		t.Errorf("期望加载 offline 档案, 实际 Provider='%s', Profile='%s'", cfg.Provider, cfg.Profile())
+	}
+
+	names, current := ListProfiles()
+	if strings.Join(names, ",") != "default,offline" || current != "offline" {
+		t.Errorf("档案列表不正确: names=%v, current=%s", names, current)
+	}
+
+	if err := UseProfile(DefaultProfile); err != nil {
+		t.Fatalf("切换回默认档案失败: %v", err)
+	}
+	if cfg := LoadConfig(); cfg.Profile() != DefaultProfile {
+		t.Errorf("期望默认档案, 实际='%s'", cfg.Profile())
+	}
+
+	if err := UseProfile("missing"); err == nil {
+		t.Error("期望切换到不存在的档案返回错误")
+	}
+	if _, err := LoadProfile("missing"); err == nil {
+		t.Error("期望加载不存在的档案返回错误")
+	}
+}
+
+func TestProfile_MissingFieldsUseDefaults(t *testing.T) {
+	tmpDir, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	configDir := filepath.Join(tmpDir, ".config", "aicommit")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("创建配置目录失败: %v", err)
+	}
+	data := `{"api_key":"sk-1","language":"en","profiles":{"work":{"api_key":"sk-work"}}}`
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(data), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+
+	cfg, err := LoadProfile("work")
+	if err != nil {
+		t.Fatalf("加载档案失败: %v", err)
+	}
+	if cfg.APIKey != "sk-work" || cfg.Model != "gpt-4o" || cfg.MaxRetries != 3 {
+		t.Errorf("未设置的字段应使用默认值: %+v", cfg)
+	}
+}
+
+func TestOpenProfile_InvalidName(t *testing.T) {
+	for _, name := range []string{" ", "my work", "a/b"} {
+		if _, err := OpenProfile(name); err == nil {
+			t.Errorf("期望档案名称 %q 返回错误", name)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/SimonGino/aicommit/internal/ai"
+	"github.com/SimonGino/aicommit/internal/secret"
+)
+
+// detectSecretBackend 自动选择密钥存储后端，测试中替换以避免访问系统钥匙串
+var detectSecretBackend = secret.Detect
+
+// secretAccount 返回 API Key 在密钥存储中的账户名
+// 主提供商为档案名，备用提供商为 "档案名/备用提供商名称"
+func secretAccount(profile, fallback string) string {
+	if fallback == "" {
+		return profile
+	}
+	return profile + "/" + fallback
+}
+
+// visitSecrets 遍历配置中主提供商和备用提供商的 API Key
+func visitSecrets(cfg *Config, fn func(account string, key *string) error) error {
+	profile := cfg.Profile()
+	if err := fn(secretAccount(profile, ""), &cfg.APIKey); err != nil {
+		return err
+	}
+	for i := range cfg.Fallbacks {
+		if err := fn(secretAccount(profile, cfg.Fallbacks[i].Name), &cfg.Fallbacks[i].APIKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visitAllSecrets 遍历配置文件中所有档案的 API Key，被修改的命名档案会重新编码
+func (f *configFile) visitAllSecrets(fn func(account string, key *string) error) error {
+	f.Config.profile = DefaultProfile
+	if err := visitSecrets(&f.Config, fn); err != nil {
+		return err
+	}
+
+	for name := range f.Profiles {
+		cfg, ok := f.lookup(name)
+		if !ok {
+			continue
+		}
+		changed := false
+		err := visitSecrets(cfg, func(account string, key *string) error {
+			before := *key
+			err := fn(account, key)
+			changed = changed || *key != before
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if changed {
+			raw, err := json.Marshal(cfg)
+			if err != nil {
+				return fmt.Errorf("序列化配置失败: %w", err)
+			}
+			f.Profiles[name] = raw
+		}
+	}
+	return nil
+}
+
+// backend 返回密钥存储后端，尚未选择时自动探测
+func (f *configFile) backend() secret.Backend {
+	if backend, err := secret.ParseBackend(f.SecretBackend); err == nil {
+		return backend
+	}
+	return detectSecretBackend()
+}
+
+// hasPlaintextSecrets 判断配置文件中是否有明文 API Key
+func (f *configFile) hasPlaintextSecrets() bool {
+	found := false
+	_ = f.visitAllSecrets(func(_ string, key *string) error {
+		found = found || *key != ""
+		return nil
+	})
+	return found
+}
+
+// storeSecrets 把明文 API Key 移入密钥存储，使用明文后端时保持不变
+func (f *configFile) storeSecrets() error {
+	backend := f.backend()
+	if backend == secret.BackendPlain || !f.hasPlaintextSecrets() {
+		return nil
+	}
+
+	store, err := secret.Open(backend, configDir())
+	if err != nil {
+		return err
+	}
+	err = f.visitAllSecrets(func(account string, key *string) error {
+		if *key == "" {
+			return nil
+		}
+		if err := store.Set(account, *key); err != nil {
+			return err
+		}
+		*key = ""
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("保存 API Key 到%s失败: %w（可使用 'aicommit config --secret-backend plain' 改为保存在配置文件中）", backend.Label(), err)
+	}
+
+	f.SecretBackend = string(backend)
+	return nil
+}
+
+// loadSecrets 从密钥存储中读取 cfg 尚未配置的 API Key
+// 只读取需要 API Key 的提供商，密钥存储在第一次读取时才打开，
+// 环境变量已提供密钥或使用 ollama 时不会询问加密文件的口令
+func (f *configFile) loadSecrets(cfg *Config) error {
+	if cfg.APIKey != "" {
+		cfg.keyBackend = secret.BackendPlain
+	}
+
+	backend, err := secret.ParseBackend(f.SecretBackend)
+	if err != nil || backend == secret.BackendPlain {
+		return nil
+	}
+	var store secret.Store
+	read := func(account string, key *string) error {
+		if store == nil {
+			if store, err = secret.Open(backend, configDir()); err != nil {
+				return err
+			}
+		}
+		value, err := store.Get(account)
+		if errors.Is(err, secret.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取 API Key 失败: %w", err)
+		}
+		*key = value
+		return nil
+	}
+
+	profile := cfg.Profile()
+	if cfg.APIKey == "" && needsAPIKey(cfg.Provider) {
+		if err := read(secretAccount(profile, ""), &cfg.APIKey); err != nil {
+			return err
+		}
+		if cfg.APIKey != "" {
+			cfg.keyBackend = backend
+			cfg.setSource("api_key", SourceGlobal)
+		}
+	}
+	for i := range cfg.Fallbacks {
+		entry := &cfg.Fallbacks[i]
+		if entry.APIKey != "" || !needsAPIKey(entry.Provider) {
+			continue
+		}
+		if err := read(secretAccount(profile, entry.Name), &entry.APIKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// needsAPIKey 判断提供商是否需要 API Key，未知的提供商按需要处理
+func needsAPIKey(provider string) bool {
+	factory, ok := ai.Lookup(provider)
+	return !ok || slices.Contains(factory.Required, ai.FieldAPIKey)
+}
+
+// migrateSecrets 把已有的明文 API Key 迁移到密钥存储，并收紧配置文件权限
+// 迁移失败时保持原样，不影响本次运行
+func (f *configFile) migrateSecrets() {
+	if f.raw == nil {
+		return
+	}
+	if f.hasPlaintextSecrets() && f.backend() != secret.BackendPlain {
+		_ = f.save()
+		return
+	}
+	if info, err := os.Stat(configFilePath()); err == nil && info.Mode().Perm()&0077 != 0 {
+		_ = os.Chmod(configFilePath(), 0600)
+	}
+}
+
+// deleteSecret 从密钥存储中删除指定账户的 API Key
+func deleteSecret(account string) error {
+	file := readConfigFile()
+	backend, err := secret.ParseBackend(file.SecretBackend)
+	if err != nil || backend == secret.BackendPlain {
+		return nil
+	}
+	store, err := secret.Open(backend, configDir())
+	if err != nil {
+		return err
+	}
+	return store.Delete(account)
+}
+
+// SetSecretBackend 切换密钥存储后端，并把所有 API Key 迁移到新后端
+func SetSecretBackend(backend secret.Backend) error {
+	file := readConfigFile()
+
+	// 从原后端读出所有 API Key
+	var oldStore secret.Store
+	var moved []string
+	if old, err := secret.ParseBackend(file.SecretBackend); err == nil && old != backend {
+		if oldStore, err = secret.Open(old, configDir()); err != nil {
+			return err
+		}
+	}
+	if oldStore != nil {
+		err := file.visitAllSecrets(func(account string, key *string) error {
+			if *key != "" {
+				return nil
+			}
+			value, err := oldStore.Get(account)
+			if errors.Is(err, secret.ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			*key = value
+			moved = append(moved, account)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("读取原有 API Key 失败: %w", err)
+		}
+	}
+
+	file.SecretBackend = string(backend)
+	if err := os.MkdirAll(configDir(), 0700); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
+	}
+	if err := file.save(); err != nil {
+		return err
+	}
+
+	// 迁移成功后从原后端删除
+	for _, account := range moved {
+		_ = oldStore.Delete(account)
+	}
+	return nil
+}
+
+// SecretBackend 返回 API Key 的存储后端，未配置 API Key 或来自环境变量时为空
+func (c *Config) SecretBackend() secret.Backend {
+	if c.APIKey == "" || c.Source("api_key") == SourceEnv {
+		return ""
+	}
+	return c.keyBackend
+}
+
+// configDir 返回全局配置目录
+func configDir() string {
+	return filepath.Dir(configFilePath())
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SimonGino/aicommit/internal/secret"
+	"github.com/zalando/go-keyring"
+)
+
+// writeConfigFile 直接写入配置文件，模拟旧版本保存的明文配置
+func writeConfigFile(t *testing.T, home, content string) string {
+	t.Helper()
+	dir := filepath.Join(home, ".config", "aicommit")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("创建配置目录失败: %v", err)
+	}
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	return path
+}
+
+func TestSecrets_MigratePlaintextToEncryptedFile(t *testing.T) {
+	home, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	t.Setenv(secret.PassphraseEnv, "test-passphrase")
+	detectSecretBackend = func() secret.Backend { return secret.BackendFile }
+
+	path := writeConfigFile(t, home, `{
+  "api_key": "sk-plaintext-key",
+  "language": "en",
+  "fallbacks": [{"name": "backup", "provider": "openai", "api_key": "sk-backup-key"}],
+  "profiles": {"work": {"provider": "azure", "api_key": "azure-key"}}
+}`)
+
+	cfg, err := loadProfile("", t.TempDir())
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.APIKey != "sk-plaintext-key" || cfg.Fallbacks[0].APIKey != "sk-backup-key" {
+		t.Errorf("迁移后应能读取 API Key: %q, %q", cfg.APIKey, cfg.Fallbacks[0].APIKey)
+	}
+	if cfg.SecretBackend() != secret.BackendFile {
+		t.Errorf("期望存储后端为 file, 实际=%s", cfg.SecretBackend())
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, key := range []string{"sk-plaintext-key", "sk-backup-key", "azure-key"} {
+		if strings.Contains(string(data), key) {
+			t.Errorf("配置文件中不应再有明文密钥 %s", key)
+		}
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("期望配置文件权限 0600, 实际=%v", info.Mode().Perm())
+	}
+
+	work, err := loadProfile("work", t.TempDir())
+	if err != nil {
+		t.Fatalf("加载档案失败: %v", err)
+	}
+	if work.APIKey != "azure-key" {
+		t.Errorf("期望读取 work 档案的 API Key, 实际=%q", work.APIKey)
+	}
+}
+
+func TestSecrets_Keyring(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	keyring.MockInit()
+	detectSecretBackend = func() secret.Backend { return secret.BackendKeyring }
+
+	cfg, _ := OpenProfile("")
+	if err := cfg.UpdateAPIKey("sk-keyring-key"); err != nil {
+		t.Fatalf("保存 API Key 失败: %v", err)
+	}
+	if data, _ := os.ReadFile(cfg.ConfigFile()); strings.Contains(string(data), "sk-keyring-key") {
+		t.Error("配置文件中不应有明文密钥")
+	}
+
+	// 修改其他配置项不影响已保存的密钥
+	cfg, _ = OpenProfile("")
+	if err := cfg.UpdateModel("gpt-4.1"); err != nil {
+		t.Fatalf("更新模型失败: %v", err)
+	}
+
+	loaded, err := loadProfile("", t.TempDir())
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if loaded.APIKey != "sk-keyring-key" || loaded.SecretBackend() != secret.BackendKeyring {
+		t.Errorf("期望从钥匙串读取 API Key, 实际=%q (%s)", loaded.APIKey, loaded.SecretBackend())
+	}
+	if loaded.Source("api_key") != SourceGlobal {
+		t.Errorf("期望来源为 global, 实际=%s", loaded.Source("api_key"))
+	}
+}
+
+// setupEncryptedFile 把 API Key 保存到加密文件，之后清除口令环境变量，模拟没有终端也没有口令的环境（如 git 钩子）
+func setupEncryptedFile(t *testing.T, home, content string) {
+	t.Helper()
+	t.Setenv(secret.PassphraseEnv, "test-passphrase")
+	detectSecretBackend = func() secret.Backend { return secret.BackendFile }
+	writeConfigFile(t, home, content)
+	if _, err := loadProfile("", t.TempDir()); err != nil {
+		t.Fatalf("迁移到加密文件失败: %v", err)
+	}
+	t.Setenv(secret.PassphraseEnv, "")
+
+	if _, err := loadProfile("", t.TempDir()); err == nil {
+		t.Fatal("没有口令时读取加密文件应失败")
+	}
+}
+
+func TestSecrets_EnvKeySkipsEncryptedFile(t *testing.T) {
+	home, cleanup := setupTestConfig(t)
+	defer cleanup()
+	setupEncryptedFile(t, home, `{"api_key": "sk-stored-key"}`)
+
+	t.Setenv("AICOMMIT_API_KEY", "sk-from-env")
+	cfg, err := loadProfile("", t.TempDir())
+	if err != nil {
+		t.Fatalf("环境变量提供 API Key 时不应读取加密文件: %v", err)
+	}
+	if cfg.APIKey != "sk-from-env" {
+		t.Errorf("期望使用环境变量中的 API Key, 实际=%q", cfg.APIKey)
+	}
+
+	// 常用环境变量在加密文件无法读取时作为替代
+	t.Setenv("AICOMMIT_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	cfg, err = loadProfile("", t.TempDir())
+	if err != nil {
+		t.Fatalf("加密文件无法读取时应改用 OPENAI_API_KEY: %v", err)
+	}
+	if cfg.APIKey != "sk-openai" {
+		t.Errorf("期望使用 OPENAI_API_KEY, 实际=%q", cfg.APIKey)
+	}
+}
+
+func TestSecrets_OllamaSkipsEncryptedFile(t *testing.T) {
+	home, cleanup := setupTestConfig(t)
+	defer cleanup()
+	setupEncryptedFile(t, home, `{
+  "api_key": "sk-stored-key",
+  "profiles": {"offline": {"provider": "ollama", "model": "llama3"}}
+}`)
+
+	cfg, err := loadProfile("offline", t.TempDir())
+	if err != nil {
+		t.Fatalf("ollama 不需要 API Key，不应读取加密文件: %v", err)
+	}
+	if cfg.Provider != "ollama" {
+		t.Errorf("期望提供商为 ollama, 实际=%s", cfg.Provider)
+	}
+
+	t.Setenv("AICOMMIT_PROVIDER", "ollama")
+	if _, err := loadProfile("", t.TempDir()); err != nil {
+		t.Errorf("通过环境变量切换到 ollama 时不应读取加密文件: %v", err)
+	}
+}
+
+func TestSetSecretBackend(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	keyring.MockInit()
+	detectSecretBackend = func() secret.Backend { return secret.BackendKeyring }
+
+	cfg, _ := OpenProfile("")
+	if err := cfg.UpdateAPIKey("sk-move-me"); err != nil {
+		t.Fatalf("保存 API Key 失败: %v", err)
+	}
+
+	if err := SetSecretBackend(secret.BackendPlain); err != nil {
+		t.Fatalf("切换存储后端失败: %v", err)
+	}
+	if data, _ := os.ReadFile(cfg.ConfigFile()); !strings.Contains(string(data), "sk-move-me") {
+		t.Error("切换为 plain 后密钥应写回配置文件")
+	}
+	if _, err := keyring.Get("aicommit", "default"); err == nil {
+		t.Error("切换后应从钥匙串删除密钥")
+	}
+
+	loaded, _ := loadProfile("", t.TempDir())
+	if loaded.APIKey != "sk-move-me" || loaded.SecretBackend() != secret.BackendPlain {
+		t.Errorf("期望明文读取 API Key, 实际=%q (%s)", loaded.APIKey, loaded.SecretBackend())
+	}
+}
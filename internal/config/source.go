@@ -0,0 +1,323 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SimonGino/aicommit/internal/ai"
+	"github.com/SimonGino/aicommit/internal/git"
+)
+
+// RepoConfigFile 仓库级配置文件名，位于 git 仓库根目录，其中的字段覆盖全局配置
+const RepoConfigFile = ".aicommit.json"
+
+// Source 配置项的来源
+// 优先级从高到低：命令行参数 > 环境变量 > 仓库配置 > 全局配置 > 默认值
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceGlobal  Source = "global"
+	SourceRepo    Source = "repo"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// rank 返回来源的优先级，数值越大优先级越高
+func (s Source) rank() int {
+	switch s {
+	case SourceGlobal:
+		return 1
+	case SourceRepo:
+		return 2
+	case SourceEnv:
+		return 3
+	case SourceFlag:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// Label 返回来源的显示名称
+func (s Source) Label() string {
+	switch s {
+	case SourceGlobal:
+		return "全局配置"
+	case SourceRepo:
+		return "仓库配置"
+	case SourceEnv:
+		return "环境变量"
+	case SourceFlag:
+		return "命令行参数"
+	default:
+		return "默认值"
+	}
+}
+
+// field 可被逐层覆盖的配置项，name 与配置文件中的 JSON 键一致
+type field struct {
+	name   string
+	ptr    func(c *Config) any // 返回字段指针：*string、*int 或 *[]string
+	secret bool                // 敏感字段不允许写在仓库配置中，显示时打码
+	// trusted 只能来自全局配置、环境变量或命令行参数
+	// 仓库配置随代码分发，不能决定 API 密钥和 diff 发往何处，也不能关闭密钥检测
+	trusted bool
+}
+
+// fields 可覆盖的配置项，顺序即 check 中的显示顺序
+var fields = []field{
+	{name: "provider", ptr: func(c *Config) any { return &c.Provider }, trusted: true},
+	{name: "api_key", ptr: func(c *Config) any { return &c.APIKey }, secret: true},
+	{name: "base_url", ptr: func(c *Config) any { return &c.BaseURL }, trusted: true},
+	{name: "model", ptr: func(c *Config) any { return &c.Model }},
+	{name: "language", ptr: func(c *Config) any { return &c.Language }},
+	{name: "azure_api_version", ptr: func(c *Config) any { return &c.AzureAPIVersion }, trusted: true},
+	{name: "max_retries", ptr: func(c *Config) any { return &c.MaxRetries }},
+	{name: "retry_base_delay", ptr: func(c *Config) any { return &c.RetryBaseDelay }},
+	{name: "diff_mode", ptr: func(c *Config) any { return &c.DiffMode }},
+	{name: "diff_budget", ptr: func(c *Config) any { return &c.DiffBudget }},
+	{name: "summary_concurrency", ptr: func(c *Config) any { return &c.SummaryConcurrency }},
+	{name: "secret_scan", ptr: func(c *Config) any { return &c.SecretScan }, trusted: true},
+	{name: "secret_allowlist", ptr: func(c *Config) any { return &c.SecretAllowlist }, trusted: true},
+	{name: "commit_types", ptr: func(c *Config) any { return &c.CommitTypes }},
+}
+
+func lookupField(name string) (field, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// set 按字符串设置字段值
+func (f field) set(c *Config, value string) error {
+	switch p := f.ptr(c).(type) {
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s 必须是整数: %s", f.name, value)
+		}
+		*p = n
+	case *[]string:
+		// 列表以逗号分隔
+		*p = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+	}
+	return nil
+}
+
+// format 返回字段值的显示形式，敏感字段打码
+func (f field) format(c *Config) string {
+	switch p := f.ptr(c).(type) {
+	case *string:
+		if f.secret {
+			return maskSecret(*p)
+		}
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *[]string:
+		return strings.Join(*p, ", ")
+	}
+	return ""
+}
+
+// validate 校验覆盖后的字段值
+func (f field) validate(c *Config) error {
+	switch f.name {
+	case "language":
+		return validateLanguage(c.Language)
+	case "max_retries":
+		if c.MaxRetries < 0 {
+			return fmt.Errorf("重试次数不能为负数: %d", c.MaxRetries)
+		}
+	case "diff_mode":
+		_, err := ai.ParseDiffMode(c.DiffMode)
+		return err
+	case "diff_budget":
+		if c.DiffBudget < 0 {
+			return fmt.Errorf("diff 预算不能为负数: %d", c.DiffBudget)
+		}
+	case "summary_concurrency":
+		if c.SummaryConcurrency < 0 {
+			return fmt.Errorf("并发数不能为负数: %d", c.SummaryConcurrency)
+		}
+	case "secret_scan", "secret_allowlist":
+		_, _, err := c.SecretScanner()
+		return err
+	case "commit_types":
+		return validateCommitTypes(c.CommitTypes)
+	}
+	return nil
+}
+
+// maskSecret 只保留首尾各 4 个字符
+func maskSecret(s string) string {
+	if len(s) > 8 {
+		return s[:4] + "..." + s[len(s)-4:]
+	}
+	if s != "" {
+		return "***"
+	}
+	return ""
+}
+
+// FieldInfo 配置项的生效值及来源
+type FieldInfo struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+	Env    string `json:"env,omitempty"` // 来源为环境变量时的变量名
+}
+
+// Fields 返回各配置项的生效值及来源
+func (c *Config) Fields() []FieldInfo {
+	infos := make([]FieldInfo, 0, len(fields))
+	for _, f := range fields {
+		info := FieldInfo{Name: f.name, Value: f.format(c), Source: c.Source(f.name)}
+		if info.Source == SourceEnv {
+			info.Env = c.envNames[f.name]
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Source 返回配置项的来源
+func (c *Config) Source(name string) Source {
+	if source, ok := c.sources[name]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// RepoConfigPath 返回生效的仓库配置文件路径，未使用仓库配置时为空
+func (c *Config) RepoConfigPath() string {
+	return c.repoFile
+}
+
+// Override 以更高优先级的来源（环境变量、命令行参数）覆盖配置项
+// 被覆盖后的配置不能再保存，避免把临时值写入全局配置
+func (c *Config) Override(name, value string, source Source) error {
+	f, ok := lookupField(name)
+	if !ok {
+		return fmt.Errorf("未知的配置项: %s", name)
+	}
+	if err := f.set(c, value); err != nil {
+		return err
+	}
+	if err := f.validate(c); err != nil {
+		return err
+	}
+	c.setSource(name, source)
+	return nil
+}
+
+func (c *Config) setSource(name string, source Source) {
+	if c.sources == nil {
+		c.sources = map[string]Source{}
+	}
+	c.sources[name] = source
+}
+
+// overridden 返回第一个来自全局配置之外的覆盖项
+func (c *Config) overridden() (string, Source, bool) {
+	for _, f := range fields {
+		switch source := c.Source(f.name); source {
+		case SourceRepo, SourceEnv, SourceFlag:
+			return f.name, source, true
+		}
+	}
+	return "", "", false
+}
+
+// markGlobal 将配置文件中出现的字段标记为来自全局配置
+func (c *Config) markGlobal(data []byte) {
+	var raw map[string]json.RawMessage
+	if json.Unmarshal(data, &raw) != nil {
+		return
+	}
+	for _, f := range fields {
+		if _, ok := raw[f.name]; ok {
+			c.setSource(f.name, SourceGlobal)
+		}
+	}
+}
+
+// applyRepoConfig 读取 dir 所在 git 仓库根目录下的 .aicommit.json 并覆盖对应字段
+// dir 为空时使用当前目录，不在 git 仓库中或文件不存在时不做任何修改
+func (c *Config) applyRepoConfig(dir string) error {
+	repo, err := git.GetRepo(dir)
+	if err != nil {
+		return nil
+	}
+	topLevel, err := repo.TopLevel()
+	if err != nil {
+		return nil
+	}
+
+	path := filepath.Join(topLevel, RepoConfigFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取仓库配置 %s 失败: %w", path, err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("解析仓库配置 %s 失败: %w", path, err)
+	}
+
+	// 拼错的配置项（如 "modle"）会被静默忽略，直接报错
+	var unknown, allowed []string
+	for name := range raw {
+		if _, ok := lookupField(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		for _, f := range fields {
+			if !f.secret && !f.trusted {
+				allowed = append(allowed, f.name)
+			}
+		}
+		sort.Strings(unknown)
+		return fmt.Errorf("仓库配置 %s 包含未知的配置项 %s（可选: %s）", path, strings.Join(unknown, ", "), strings.Join(allowed, ", "))
+	}
+
+	for _, f := range fields {
+		value, ok := raw[f.name]
+		if !ok {
+			continue
+		}
+		if f.secret || f.trusted {
+			return fmt.Errorf("仓库配置 %s 不能包含 %s，请使用 'aicommit config' 或环境变量配置", path, f.name)
+		}
+		if err := json.Unmarshal(value, f.ptr(c)); err != nil {
+			return fmt.Errorf("仓库配置 %s 中的 %s 无效: %w", path, f.name, err)
+		}
+		if err := f.validate(c); err != nil {
+			return fmt.Errorf("仓库配置 %s 无效: %w", path, err)
+		}
+		c.setSource(f.name, SourceRepo)
+	}
+
+	c.repoFile = path
+	return nil
+}
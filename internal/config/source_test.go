@@ -0,0 +1,210 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SimonGino/aicommit/internal/ai"
+)
+
+// setupTestRepo 创建临时 git 仓库并写入 .aicommit.json，返回仓库中的子目录
+func setupTestRepo(t *testing.T, repoConfig string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := exec.Command("git", "init", "-q", dir).Run(); err != nil {
+		t.Skipf("git 不可用: %v", err)
+	}
+	if repoConfig != "" {
+		if err := os.WriteFile(filepath.Join(dir, RepoConfigFile), []byte(repoConfig), 0644); err != nil {
+			t.Fatalf("写入仓库配置失败: %v", err)
+		}
+	}
+
+	sub := filepath.Join(dir, "pkg", "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("创建子目录失败: %v", err)
+	}
+	return sub
+}
+
+func TestRepoConfig_OverridesGlobal(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	global := LoadConfig()
+	global.Model = "gpt-4o-mini"
+	global.APIKey = "sk-global-key"
+	if err := global.Save(); err != nil {
+		t.Fatalf("保存全局配置失败: %v", err)
+	}
+
+	dir := setupTestRepo(t, `{"language": "zh-CN", "model": "gpt-4.1"}`)
+	cfg, err := loadProfile("", dir)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+
+	if cfg.Language != "zh-CN" || cfg.Model != "gpt-4.1" || cfg.APIKey != "sk-global-key" {
+		t.Errorf("合并结果不正确: Language=%s, Model=%s, APIKey=%s", cfg.Language, cfg.Model, cfg.APIKey)
+	}
+
+	want := map[string]Source{
+		"language": SourceRepo,
+		"model":    SourceRepo,
+		"api_key":  SourceGlobal,
+		"base_url": SourceDefault,
+	}
+	for name, source := range want {
+		if got := cfg.Source(name); got != source {
+			t.Errorf("%s 期望来源 %s, 实际 %s", name, source, got)
+		}
+	}
+	if cfg.RepoConfigPath() == "" {
+		t.Error("期望记录仓库配置文件路径")
+	}
+
+	if err := cfg.Save(); err == nil {
+		t.Error("包含仓库覆盖项的配置不应被保存")
+	}
+}
+
+func TestRepoConfig_RejectsSecrets(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	dir := setupTestRepo(t, `{"api_key": "sk-leaked"}`)
+	if _, err := loadProfile("", dir); err == nil {
+		t.Error("期望仓库配置中的 api_key 返回错误")
+	}
+}
+
+func TestRepoConfig_RejectsEndpoint(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	// 仓库不能把请求连同全局配置中的 API 密钥转发到其他服务
+	for _, repoConfig := range []string{
+		`{"base_url": "https://attacker.example/v1"}`,
+		`{"provider": "ollama"}`,
+		`{"azure_api_version": "2024-02-15-preview"}`,
+	} {
+		if _, err := loadProfile("", setupTestRepo(t, repoConfig)); err == nil {
+			t.Errorf("期望仓库配置 %s 返回错误", repoConfig)
+		}
+	}
+}
+
+func TestRepoConfig_RejectsUnknownKeys(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	dir := setupTestRepo(t, `{"modle": "gpt-4.1", "language": "en"}`)
+	_, err := loadProfile("", dir)
+	if err == nil || !strings.Contains(err.Error(), "modle") {
+		t.Errorf("期望拼错的配置项返回错误, 实际=%v", err)
+	}
+}
+
+func TestRepoConfig_InvalidLanguage(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	dir := setupTestRepo(t, `{"language": "fr"}`)
+	if _, err := loadProfile("", dir); err == nil {
+		t.Error("期望不支持的语言返回错误")
+	}
+}
+
+func TestRepoConfig_NoFile(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	cfg, err := loadProfile("", setupTestRepo(t, ""))
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.RepoConfigPath() != "" || cfg.Source("language") != SourceDefault {
+		t.Errorf("没有仓库配置时不应产生覆盖, 来源=%s", cfg.Source("language"))
+	}
+}
+
+func TestOverride(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	cfg := LoadConfig()
+	if err := cfg.Override("max_retries", "5", SourceFlag); err != nil {
+		t.Fatalf("覆盖配置失败: %v", err)
+	}
+	if cfg.MaxRetries != 5 || cfg.Source("max_retries") != SourceFlag {
+		t.Errorf("期望 MaxRetries=5 且来源为 flag, 实际=%d, %s", cfg.MaxRetries, cfg.Source("max_retries"))
+	}
+
+	if err := cfg.Override("max_retries", "many", SourceFlag); err == nil {
+		t.Error("期望非整数返回错误")
+	}
+	if err := cfg.Override("unknown", "x", SourceFlag); err == nil {
+		t.Error("期望未知配置项返回错误")
+	}
+}
+
+func TestRepoConfig_SecretScan(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	global := LoadConfig()
+	global.SecretScan = "abort"
+	if err := global.Save(); err != nil {
+		t.Fatalf("保存全局配置失败: %v", err)
+	}
+
+	// 仓库不能降低检测级别或放行所有内容
+	for _, repoConfig := range []string{
+		`{"secret_scan": "off"}`,
+		`{"secret_allowlist": [".*"]}`,
+	} {
+		if _, err := loadProfile("", setupTestRepo(t, repoConfig)); err == nil {
+			t.Errorf("期望仓库配置 %s 返回错误", repoConfig)
+		}
+	}
+
+	cfg, err := loadProfile("", setupTestRepo(t, ""))
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if _, mode, err := cfg.SecretScanner(); err != nil || mode != "abort" {
+		t.Errorf("期望使用全局配置的 abort, 实际=%s, %v", mode, err)
+	}
+}
+
+func TestRepoConfig_CommitTypes(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	cfg, err := loadProfile("", setupTestRepo(t, ""))
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if !slices.Equal(cfg.LintRules().Types, ai.CommitTypeNames()) {
+		t.Errorf("未配置时应使用默认类型, 实际=%v", cfg.LintRules().Types)
+	}
+
+	cfg, err = loadProfile("", setupTestRepo(t, `{"commit_types": ["feat", "fix", "perf"]}`))
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	want := []string{"feat", "fix", "perf"}
+	if !slices.Equal(cfg.LintRules().Types, want) || !slices.Equal(cfg.ProviderConfig("en").CommitTypes, want) {
+		t.Errorf("生成和检查应使用仓库配置的类型, lint=%v, provider=%v", cfg.LintRules().Types, cfg.ProviderConfig("en").CommitTypes)
+	}
+
+	for _, invalid := range []string{`["Feat"]`, `["feat", "feat"]`, `["bug-fix"]`} {
+		if _, err := loadProfile("", setupTestRepo(t, `{"commit_types": `+invalid+`}`)); err == nil {
+			t.Errorf("期望无效的提交类型 %s 返回错误", invalid)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -122,10 +123,21 @@ func (r *Repository) GetStagedChanges() ([]string, error) {
 
 // GetDiff 获取指定文件的差异内容
 func (r *Repository) GetDiff(staged bool) (string, error) {
+	return r.GetDiffExcluding(staged, nil)
+}
+
+// GetDiffExcluding 获取差异内容，排除指定的文件（路径相对于仓库根目录）
+func (r *Repository) GetDiffExcluding(staged bool, exclude []string) (string, error) {
 	args := []string{"diff"}
 	if staged {
 		args = append(args, "--cached")
 	}
+	if len(exclude) > 0 {
+		args = append(args, "--")
+		for _, file := range exclude {
+			args = append(args, ":(exclude,top,literal)"+file)
+		}
+	}
 
 	cmd := exec.Command("git", args...)
 	cmd.Dir = r.path
@@ -159,6 +171,18 @@ func (r *Repository) GetCurrentBranch() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// TopLevel 获取仓库根目录
+func (r *Repository) TopLevel() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("获取仓库根目录失败: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
 // Commit 提交更改
 func (r *Repository) Commit(message string) error {
 	cmd := exec.Command("git", "commit", "-m", message)
@@ -181,6 +205,17 @@ func (r *Repository) StageAll() error {
 	return nil
 }
 
+// StageTracked 暂存已跟踪文件的修改和删除，不包含未跟踪的文件
+func (r *Repository) StageTracked() error {
+	cmd := exec.Command("git", "add", "--update")
+	cmd.Dir = r.path
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("暂存更改失败: %w", err)
+	}
+
+	return nil
+}
+
 func (r *Repository) GetUntrackedFiles() ([]string, error) {
 	cmd := exec.Command("git", "ls-files", "--others", "--exclude-standard")
 	cmd.Dir = r.path
@@ -348,3 +383,82 @@ func (r *Repository) GetCommits(authorEmail string, since, until string) ([]stri
 
 	return filteredCommits, nil
 }
+
+// LogEntry 一条提交记录
+type LogEntry struct {
+	Hash    string
+	Message string
+}
+
+// GetCommitMessages 按时间顺序返回 revRange（如 origin/main..HEAD）中非合并提交的完整提交信息
+func (r *Repository) GetCommitMessages(revRange string) ([]LogEntry, error) {
+	// 用不会出现在提交信息中的控制字符分隔哈希、提交信息和记录
+	cmd := exec.Command("git", "log", "--no-merges", "--reverse", "--format=%H%x1f%B%x1e", revRange, "--")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("获取提交记录失败: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("获取提交记录失败: %w", err)
+	}
+
+	var entries []LogEntry
+	for _, record := range strings.Split(string(output), "\x1e") {
+		hash, message, ok := strings.Cut(strings.TrimLeft(record, "\n"), "\x1f")
+		if !ok {
+			continue
+		}
+		entries = append(entries, LogEntry{Hash: hash, Message: strings.TrimRight(message, "\n")})
+	}
+	return entries, nil
+}
+
+// CommentChar 返回提交信息文件中注释行的前缀（core.commentChar），未配置或为 auto 时返回 "#"
+func (r *Repository) CommentChar() string {
+	cmd := exec.Command("git", "config", "core.commentChar")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "#"
+	}
+	char := strings.TrimSpace(string(output))
+	if char == "" || char == "auto" {
+		return "#"
+	}
+	return char
+}
+
+// scissorsLine "git commit -v" 在提交信息文件中附加 diff 时使用的分隔线，之后的内容都不属于提交信息
+const scissorsLine = " ------------------------ >8 ------------------------"
+
+// CleanMessage 按 git 的规则清理提交信息文件的内容：去掉注释行和分隔线之后的内容，以及首尾空行
+func CleanMessage(message, commentChar string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if line == commentChar+scissorsLine {
+			break
+		}
+		if strings.HasPrefix(line, commentChar) {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// HooksDir 返回 git 钩子所在目录，配置了 core.hooksPath 时返回该目录
+func (r *Repository) HooksDir() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-path", "hooks")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("获取钩子目录失败: %w", err)
+	}
+
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.path, dir)
+	}
+	return dir, nil
+}
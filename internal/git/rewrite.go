@@ -0,0 +1,363 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ResolveCommit 把提交名称（分支、HEAD~2、短哈希等）解析为完整哈希
+func (r *Repository) ResolveCommit(rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("找不到提交: %s", rev)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ParentOf 返回提交的第一个父提交，根提交返回空字符串
+func (r *Repository) ParentOf(hash string) (string, error) {
+	cmd := exec.Command("git", "rev-list", "--parents", "-n", "1", hash)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("获取父提交失败: %w", err)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) < 2 {
+		return "", nil
+	}
+	return fields[1], nil
+}
+
+// GetCommitMessage 获取提交的完整提交信息
+func (r *Repository) GetCommitMessage(rev string) (string, error) {
+	cmd := exec.Command("git", "log", "-1", "--format=%B", rev, "--")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("获取提交信息失败: %w", err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// diffArgs 返回比较 base 和 rev 的 diff 参数
+// base 为空时与空树比较（根提交），rev 为空时与暂存区比较
+func (r *Repository) diffArgs(base, rev string) ([]string, error) {
+	if base == "" {
+		tree, err := r.emptyTree()
+		if err != nil {
+			return nil, err
+		}
+		base = tree
+	}
+	if rev == "" {
+		return []string{"diff", "--cached", base}, nil
+	}
+	return []string{"diff", base, rev}, nil
+}
+
+// emptyTree 返回空树的哈希，与仓库使用的哈希算法一致
+func (r *Repository) emptyTree() (string, error) {
+	cmd := exec.Command("git", "hash-object", "-t", "tree", "--stdin")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("获取空树哈希失败: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetChangesBetween 获取 base 到 rev 之间变更的文件
+// base 为空时表示根提交之前，rev 为空时表示暂存区
+func (r *Repository) GetChangesBetween(base, rev string) ([]string, error) {
+	args, err := r.diffArgs(base, rev)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("git", append(args, "--name-only")...)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("获取变更文件失败: %w", err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			files = append(files, unquoteGitPath(line))
+		}
+	}
+	return files, nil
+}
+
+// GetDiffBetween 获取 base 到 rev 之间的差异内容，排除指定的文件（路径相对于仓库根目录）
+// base 和 rev 的含义与 GetChangesBetween 相同
+func (r *Repository) GetDiffBetween(base, rev string, exclude []string) (string, error) {
+	args, err := r.diffArgs(base, rev)
+	if err != nil {
+		return "", err
+	}
+	if len(exclude) > 0 {
+		args = append(args, "--")
+		for _, file := range exclude {
+			args = append(args, ":(exclude,top,literal)"+file)
+		}
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("获取差异内容失败: %w", err)
+	}
+
+	return string(output), nil
+}
+
+// IsAncestor 判断 ancestor 是否是 rev 的祖先（包括 rev 本身）
+func (r *Repository) IsAncestor(ancestor, rev string) (bool, error) {
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", ancestor, rev)
+	cmd.Dir = r.path
+	err := cmd.Run()
+	if err == nil {
+		return true, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("检查提交关系失败: %w", err)
+}
+
+// RemoteBranchesContaining 返回包含该提交的远程跟踪分支，非空说明提交已经推送
+func (r *Repository) RemoteBranchesContaining(rev string) ([]string, error) {
+	cmd := exec.Command("git", "for-each-ref", "--contains", rev, "--format=%(refname:short)", "refs/remotes")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("检查远程分支失败: %w", err)
+	}
+
+	var branches []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			branches = append(branches, line)
+		}
+	}
+	return branches, nil
+}
+
+// HasMerges 判断 base 之后到 HEAD 的提交中是否有合并提交，base 为空时检查全部历史
+func (r *Repository) HasMerges(base string) (bool, error) {
+	revRange := "HEAD"
+	if base != "" {
+		revRange = base + "..HEAD"
+	}
+	cmd := exec.Command("git", "rev-list", "--merges", "-n", "1", revRange)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("检查合并提交失败: %w", err)
+	}
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+// AmendCommit 用新的提交信息修改 HEAD，暂存区的更改一并加入
+func (r *Repository) AmendCommit(message string) error {
+	cmd := exec.Command("git", "commit", "--amend", "-m", message)
+	cmd.Dir = r.path
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("修改提交失败: %w", err)
+	}
+
+	return nil
+}
+
+// RewordCommits 通过一次非交互的 rebase 修改 base 之后各提交的提交信息，文件内容保持不变
+// messages 以完整哈希为键，base 为空时从根提交开始；失败时中止 rebase，恢复原来的状态
+func (r *Repository) RewordCommits(base string, messages map[string]string) error {
+	// 失败时会执行 rebase --abort，不能影响用户自己正在进行的 rebase
+	if r.rebaseInProgress() {
+		return fmt.Errorf("正在进行 rebase，请先完成或使用 'git rebase --abort' 中止")
+	}
+
+	revRange := "HEAD"
+	if base != "" {
+		revRange = base + "..HEAD"
+	}
+	cmd := exec.Command("git", "rev-list", "--reverse", revRange)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("获取提交列表失败: %w", err)
+	}
+	commits := strings.Fields(string(output))
+
+	dir, err := os.MkdirTemp("", "aicommit-rebase-")
+	if err != nil {
+		return fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 自己生成 rebase 的待办列表：每个需要修改的提交在 pick 之后立即用新的提交信息 amend
+	// 文件内容没有变化，amend 时跳过 pre-commit 等钩子；commit-msg 钩子在 amend 之前单独执行，
+	// 钩子可以修改提交信息，拒绝时 rebase 失败并中止
+	commitMsgHook, err := r.commitMsgHook()
+	if err != nil {
+		return err
+	}
+	var todo strings.Builder
+	for i, hash := range commits {
+		fmt.Fprintf(&todo, "pick %s\n", hash)
+		message, ok := messages[hash]
+		if !ok {
+			continue
+		}
+		msgFile := filepath.Join(dir, fmt.Sprintf("message-%d", i))
+		if err := os.WriteFile(msgFile, []byte(message+"\n"), 0600); err != nil {
+			return fmt.Errorf("写入提交信息失败: %w", err)
+		}
+		quoted := ShellQuote(filepath.ToSlash(msgFile))
+		todo.WriteString("exec ")
+		if commitMsgHook != "" {
+			fmt.Fprintf(&todo, "%s %s && ", ShellQuote(filepath.ToSlash(commitMsgHook)), quoted)
+		}
+		fmt.Fprintf(&todo, "git commit --amend --allow-empty --no-verify -F %s\n", quoted)
+	}
+	todoFile := filepath.Join(dir, "todo")
+	if err := os.WriteFile(todoFile, []byte(todo.String()), 0600); err != nil {
+		return fmt.Errorf("写入 rebase 待办列表失败: %w", err)
+	}
+
+	args := []string{"rebase", "--interactive", "--autostash", "--keep-empty"}
+	if base == "" {
+		args = append(args, "--root")
+	} else {
+		args = append(args, base)
+	}
+	cmd = exec.Command("git", args...)
+	cmd.Dir = r.path
+	// 用准备好的待办列表替换 git 生成的列表，其余需要编辑器的地方保持原样
+	cmd.Env = append(os.Environ(),
+		"GIT_SEQUENCE_EDITOR=cp "+ShellQuote(filepath.ToSlash(todoFile)),
+		"GIT_EDITOR=:",
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		abort := exec.Command("git", "rebase", "--abort")
+		abort.Dir = r.path
+		_ = abort.Run()
+		if message := rebaseFailure(string(output)); message != "" {
+			return fmt.Errorf("修改提交信息失败，已中止 rebase: %s", message)
+		}
+		return fmt.Errorf("修改提交信息失败，已中止 rebase: %w", err)
+	}
+
+	return nil
+}
+
+// rebaseFailure 从 rebase 的输出中提取失败原因
+// 去掉用 \r 刷新的进度和 "git rebase --continue" 的提示，rebase 已经中止，这些提示不再适用
+func rebaseFailure(output string) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if i := strings.LastIndex(line, "\r"); i >= 0 {
+			line = line[i+1:]
+		}
+		if strings.HasPrefix(line, "You can fix the problem") {
+			break
+		}
+		if line = strings.TrimSpace(strings.TrimPrefix(line, "\033[K")); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TreeOf 返回提交的树对象哈希，只修改提交信息时树保持不变
+func (r *Repository) TreeOf(rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", rev+"^{tree}")
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("找不到提交: %s", rev)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// UpdateRef 把引用指向指定的提交，引用不存在时创建
+func (r *Repository) UpdateRef(ref, hash string) error {
+	cmd := exec.Command("git", "update-ref", ref, hash)
+	cmd.Dir = r.path
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("更新引用 %s 失败: %s", ref, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// DeleteRef 删除引用
+func (r *Repository) DeleteRef(ref string) error {
+	cmd := exec.Command("git", "update-ref", "-d", ref)
+	cmd.Dir = r.path
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("删除引用 %s 失败: %s", ref, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ResetKeep 把当前分支移动到 rev，保留工作区中未提交的修改（git reset --keep）
+func (r *Repository) ResetKeep(rev string) error {
+	cmd := exec.Command("git", "reset", "--keep", rev)
+	cmd.Dir = r.path
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("重置分支失败: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// rebaseInProgress 判断是否有未完成的 rebase
+func (r *Repository) rebaseInProgress() bool {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		cmd := exec.Command("git", "rev-parse", "--git-path", name)
+		cmd.Dir = r.path
+		output, err := cmd.Output()
+		if err != nil {
+			continue
+		}
+		dir := strings.TrimSpace(string(output))
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(r.path, dir)
+		}
+		if _, err := os.Stat(dir); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// commitMsgHook 返回可执行的 commit-msg 钩子路径，没有时返回空字符串
+func (r *Repository) commitMsgHook() (string, error) {
+	dir, err := r.HooksDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "commit-msg")
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", nil
+	}
+	// 与 git 一致，没有执行权限的钩子不执行（Windows 上没有执行权限位）
+	if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+		return "", nil
+	}
+	return path, nil
+}
+
+// ShellQuote 用单引号包裹字符串，使其在 sh 中按原样传递
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
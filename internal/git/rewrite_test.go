@@ -0,0 +1,100 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupTestRepo 创建包含两个提交的临时仓库，返回仓库和两个提交的哈希
+func setupTestRepo(t *testing.T) (*Repository, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s 失败: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+	run("init", "-q")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatalf("写入文件失败: %v", err)
+		}
+		run("add", name)
+		run("commit", "-q", "-m", "add "+name)
+	}
+
+	repo, err := GetRepo(dir)
+	if err != nil {
+		t.Fatalf("打开仓库失败: %v", err)
+	}
+	var commits []string
+	for _, rev := range []string{"HEAD~1", "HEAD"} {
+		hash, err := repo.ResolveCommit(rev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, hash)
+	}
+	return repo, commits
+}
+
+// writeHook 在仓库中安装可执行的钩子脚本
+func writeHook(t *testing.T, repo *Repository, name, script string) {
+	t.Helper()
+	dir, err := repo.HooksDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("创建钩子目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("写入钩子失败: %v", err)
+	}
+}
+
+func TestRewordCommits_SkipsPreCommitHook(t *testing.T) {
+	repo, commits := setupTestRepo(t)
+	writeHook(t, repo, "pre-commit", "exit 1")
+	writeHook(t, repo, "commit-msg", `echo "Signed-off-by: test" >> "$1"`)
+
+	if err := repo.RewordCommits(commits[0], map[string]string{commits[1]: "feat: add b"}); err != nil {
+		t.Fatalf("pre-commit 钩子不应影响修改提交信息: %v", err)
+	}
+	message, err := repo.GetCommitMessage("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != "feat: add b\nSigned-off-by: test" {
+		t.Errorf("期望使用 commit-msg 钩子修改后的提交信息, 实际=%q", message)
+	}
+}
+
+func TestRewordCommits_CommitMsgHookRejects(t *testing.T) {
+	repo, commits := setupTestRepo(t)
+	writeHook(t, repo, "commit-msg", `echo "rejected by hook" >&2; exit 1`)
+
+	err := repo.RewordCommits("", map[string]string{commits[0]: "feat: add a"})
+	if err == nil || !strings.Contains(err.Error(), "rejected by hook") {
+		t.Fatalf("期望 commit-msg 钩子拒绝后返回钩子的输出, 实际=%v", err)
+	}
+	if head, _ := repo.ResolveCommit("HEAD"); head != commits[1] {
+		t.Errorf("钩子拒绝后历史不应改变, HEAD=%s", head)
+	}
+	if repo.rebaseInProgress() {
+		t.Error("钩子拒绝后应中止 rebase")
+	}
+}
@@ -0,0 +1,140 @@
+// Package hook 安装和卸载 prepare-commit-msg 钩子，让直接使用 git commit 时也能自动生成提交信息
+package hook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SimonGino/aicommit/internal/git"
+)
+
+const (
+	// Name 钩子文件名
+	Name = "prepare-commit-msg"
+	// ChainedName 安装前已有的钩子改名后的文件名，aicommit 的钩子会先执行它
+	ChainedName = Name + ".pre-aicommit"
+	// marker 标识由 aicommit 生成的钩子
+	marker = "# aicommit prepare-commit-msg hook"
+)
+
+// Status 钩子目录中 prepare-commit-msg 的安装状态
+type Status struct {
+	// Path 钩子文件路径
+	Path string
+	// Installed 钩子由 aicommit 安装
+	Installed bool
+	// Foreign 存在不是由 aicommit 安装的钩子
+	Foreign bool
+	// Chained 安装前已有的钩子的路径，没有时为空
+	Chained string
+}
+
+// Check 返回 dir 中钩子的安装状态
+func Check(dir string) (Status, error) {
+	status := Status{Path: filepath.Join(dir, Name)}
+	data, err := os.ReadFile(status.Path)
+	switch {
+	case os.IsNotExist(err):
+		return status, nil
+	case err != nil:
+		return status, fmt.Errorf("读取钩子 %s 失败: %w", status.Path, err)
+	}
+
+	if !isOurs(data) {
+		status.Foreign = true
+		return status, nil
+	}
+	status.Installed = true
+	if chained := filepath.Join(dir, ChainedName); fileExists(chained) {
+		status.Chained = chained
+	}
+	return status, nil
+}
+
+// Install 在 dir 中写入调用 executable 的钩子
+// 已有其他钩子时改名为 ChainedName，由新钩子先执行；已安装时只更新 executable 的路径
+func Install(dir, executable string) (Status, error) {
+	status, err := Check(dir)
+	if err != nil {
+		return status, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return status, fmt.Errorf("创建钩子目录失败: %w", err)
+	}
+
+	if status.Foreign {
+		chained := filepath.Join(dir, ChainedName)
+		if fileExists(chained) {
+			return status, fmt.Errorf("%s 已存在，无法保存原有的钩子", chained)
+		}
+		if err := os.Rename(status.Path, chained); err != nil {
+			return status, fmt.Errorf("保存原有的钩子失败: %w", err)
+		}
+		status.Foreign = false
+		status.Chained = chained
+	}
+
+	if err := os.WriteFile(status.Path, []byte(script(executable)), 0755); err != nil {
+		return status, fmt.Errorf("写入钩子失败: %w", err)
+	}
+	// 覆盖已有文件时 WriteFile 不会修改权限
+	if err := os.Chmod(status.Path, 0755); err != nil {
+		return status, fmt.Errorf("设置钩子权限失败: %w", err)
+	}
+	status.Installed = true
+	return status, nil
+}
+
+// Uninstall 删除 aicommit 安装的钩子，并恢复安装前已有的钩子
+// 返回恢复的钩子路径，没有时为空
+func Uninstall(dir string) (string, error) {
+	status, err := Check(dir)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case status.Foreign:
+		return "", fmt.Errorf("%s 不是由 aicommit 安装的，未做修改", status.Path)
+	case !status.Installed:
+		return "", fmt.Errorf("没有安装 aicommit 钩子")
+	}
+
+	if err := os.Remove(status.Path); err != nil {
+		return "", fmt.Errorf("删除钩子失败: %w", err)
+	}
+	if status.Chained == "" {
+		return "", nil
+	}
+	if err := os.Rename(status.Chained, status.Path); err != nil {
+		return "", fmt.Errorf("恢复原有的钩子失败: %w", err)
+	}
+	return status.Path, nil
+}
+
+// script 返回钩子脚本：先执行原有的钩子，再调用 aicommit 填写提交信息
+// aicommit 失败时不阻止提交，用户可以照常手动填写
+func script(executable string) string {
+	return `#!/bin/sh
+` + marker + `
+# 由 'aicommit hook install' 生成，使用 'aicommit hook uninstall' 删除
+
+chained="$(dirname "$0")/` + ChainedName + `"
+if [ -x "$chained" ]; then
+	"$chained" "$@" || exit $?
+fi
+
+` + git.ShellQuote(filepath.ToSlash(executable)) + ` hook run "$@" || true
+`
+}
+
+// isOurs 判断钩子是否由 aicommit 生成
+func isOurs(data []byte) bool {
+	return strings.Contains(string(data), marker)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,118 @@
+package hook
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeScript 写入可执行的 sh 脚本
+func writeScript(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("写入脚本失败: %v", err)
+	}
+}
+
+func TestInstall_Fresh(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hooks")
+	status, err := Install(dir, "/usr/local/bin/aicommit")
+	if err != nil {
+		t.Fatalf("安装失败: %v", err)
+	}
+	if !status.Installed || status.Chained != "" {
+		t.Errorf("状态错误: %+v", status)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, Name))
+	if err != nil {
+		t.Fatalf("读取钩子失败: %v", err)
+	}
+	if !strings.Contains(string(data), `'/usr/local/bin/aicommit' hook run "$@"`) {
+		t.Errorf("钩子应调用 aicommit hook run:\n%s", data)
+	}
+
+	// 重复安装只更新路径
+	if _, err := Install(dir, "/opt/aicommit"); err != nil {
+		t.Fatalf("重复安装失败: %v", err)
+	}
+	if status, _ := Check(dir); !status.Installed || status.Chained != "" {
+		t.Errorf("重复安装不应把自己当作原有钩子: %+v", status)
+	}
+}
+
+func TestInstall_ChainsExistingHook(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, filepath.Join(dir, Name), "echo existing\n")
+
+	if status, _ := Check(dir); !status.Foreign || status.Installed {
+		t.Errorf("应识别为其他钩子: %+v", status)
+	}
+	status, err := Install(dir, "aicommit")
+	if err != nil {
+		t.Fatalf("安装失败: %v", err)
+	}
+	if status.Chained != filepath.Join(dir, ChainedName) {
+		t.Errorf("原有钩子应被保存, 实际=%+v", status)
+	}
+
+	restored, err := Uninstall(dir)
+	if err != nil {
+		t.Fatalf("卸载失败: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, Name))
+	if restored == "" || string(data) != "#!/bin/sh\necho existing\n" {
+		t.Errorf("卸载后应恢复原有钩子, 实际=%q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ChainedName)); !os.IsNotExist(err) {
+		t.Error("卸载后不应保留改名的钩子")
+	}
+}
+
+func TestUninstall_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Uninstall(dir); err == nil {
+		t.Error("未安装时应返回错误")
+	}
+	writeScript(t, filepath.Join(dir, Name), "exit 0\n")
+	if _, err := Uninstall(dir); err == nil {
+		t.Error("不应删除其他工具的钩子")
+	}
+}
+
+func TestScript_RunsChainedHookFirst(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("需要 sh")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh 不可用")
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	writeScript(t, filepath.Join(dir, Name), `echo "original $*" >> "`+log+`"`+"\n")
+	fake := filepath.Join(dir, "fake aicommit")
+	writeScript(t, fake, `echo "aicommit $*" >> "`+log+`"; exit 1`+"\n")
+
+	if _, err := Install(dir, fake); err != nil {
+		t.Fatalf("安装失败: %v", err)
+	}
+	if out, err := exec.Command(filepath.Join(dir, Name), "MSG", "message").CombinedOutput(); err != nil {
+		t.Fatalf("aicommit 失败不应阻止提交: %v\n%s", err, out)
+	}
+
+	data, _ := os.ReadFile(log)
+	if string(data) != "original MSG message\naicommit hook run MSG message\n" {
+		t.Errorf("执行顺序或参数错误:\n%s", data)
+	}
+
+	// 原有钩子失败时中止提交
+	writeScript(t, filepath.Join(dir, ChainedName), "exit 3\n")
+	err := exec.Command(filepath.Join(dir, Name), "MSG").Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 3 {
+		t.Errorf("应返回原有钩子的退出码, 实际=%v", err)
+	}
+}
@@ -0,0 +1,198 @@
+// Package ignore 解析 gitignore 语法的 .aicommitignore，决定哪些文件的内容不发送给模型
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName 忽略规则文件名，位于 git 仓库根目录
+const FileName = ".aicommitignore"
+
+// DefaultPatterns 内置的忽略规则：常见的依赖锁文件体积大且对提交消息没有帮助
+// .aicommitignore 中可以用 "!go.sum" 等规则重新包含
+var DefaultPatterns = []string{
+	"go.sum",
+	"package-lock.json",
+	"npm-shrinkwrap.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"bun.lockb",
+	"Cargo.lock",
+	"Gemfile.lock",
+	"composer.lock",
+	"poetry.lock",
+	"Pipfile.lock",
+	"uv.lock",
+	"pubspec.lock",
+	"Podfile.lock",
+	"mix.lock",
+}
+
+// rule 一条编译后的规则
+type rule struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// Matcher 按 gitignore 语义匹配文件路径，后面的规则优先
+type Matcher struct {
+	rules []rule
+}
+
+// New 根据规则列表创建匹配器，空行、注释和无效的规则会被忽略
+func New(patterns []string) *Matcher {
+	m := &Matcher{}
+	for _, p := range patterns {
+		if r, ok := compile(p); ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+	return m
+}
+
+// Load 读取 root 目录下的 .aicommitignore，并追加在内置规则之后
+// 文件不存在时只使用内置规则
+func Load(root string) (*Matcher, error) {
+	patterns := append([]string(nil), DefaultPatterns...)
+
+	path := filepath.Join(root, FileName)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return New(patterns), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+	}
+	return New(patterns), nil
+}
+
+// Match 判断相对于仓库根目录的路径是否被忽略
+func (m *Matcher) Match(path string) bool {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "/")
+	ignored := false
+	for _, r := range m.rules {
+		if r.re.MatchString(path) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// Split 把文件分为保留和忽略两组，保持原有顺序
+func (m *Matcher) Split(files []string) (kept, ignored []string) {
+	for _, f := range files {
+		if m.Match(f) {
+			ignored = append(ignored, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	return kept, ignored
+}
+
+// compile 把一条 gitignore 规则转换为正则表达式
+func compile(pattern string) (rule, bool) {
+	pattern = trimTrailingSpaces(pattern)
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return rule{}, false
+	}
+
+	var r rule
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\!`) || strings.HasPrefix(pattern, `\#`) {
+		pattern = pattern[1:]
+	}
+
+	// 以 "/" 结尾只匹配目录，即目录下的所有文件
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	// 中间或开头包含 "/" 的规则相对于根目录，否则匹配任意层级
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return rule{}, false
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored && !strings.HasPrefix(pattern, "**") {
+		b.WriteString("(?:.*/)?")
+	}
+	b.WriteString(translate(pattern))
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return rule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+// translate 把通配符转换为正则表达式：* 和 ? 不匹配 "/"，** 匹配任意层目录
+func translate(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// trimTrailingSpaces 去掉未转义的行尾空白
+func trimTrailingSpaces(s string) string {
+	s = strings.TrimRight(s, "\r")
+	for strings.HasSuffix(s, " ") && !strings.HasSuffix(s, `\ `) {
+		s = s[:len(s)-1]
+	}
+	return strings.ReplaceAll(s, `\ `, " ")
+}
@@ -0,0 +1,90 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*.pem", "certs/dev.pem", true},
+		{"*.pem", "main.go", false},
+		{"go.sum", "go.sum", true},
+		{"go.sum", "tools/go.sum", true},
+		{"/go.sum", "tools/go.sum", false},
+		{"testdata/", "pkg/testdata/a.txt", true},
+		{"testdata/", "testdata", false},
+		{"testdata/*", "testdata/sub/a.txt", true},
+		{"docs/*.md", "docs/guide.md", true},
+		{"docs/*.md", "src/docs/guide.md", false},
+		{"docs/*.md", "docs/v1/guide.md", false},
+		{"vendor", "vendor/github.com/x/y.go", true},
+		{"**/*.pb.go", "api/v1/user.pb.go", true},
+		{"**/*.pb.go", "user.pb.go", true},
+		{"api/**/gen.go", "api/gen.go", true},
+		{"api/**/gen.go", "api/a/b/gen.go", true},
+		{"dist/**", "dist/js/app.js", true},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"[ab].go", "a.go", true},
+		{"[!ab].go", "a.go", false},
+		{"# comment", "# comment", false},
+		{`\#notes`, "#notes", true},
+		{"   ", "x", false},
+	}
+	for _, tt := range tests {
+		if got := New([]string{tt.pattern}).Match(tt.path); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, 期望 %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatch_Negation(t *testing.T) {
+	m := New([]string{"*.lock", "!Cargo.lock", "generated/", "!generated/keep.go"})
+
+	tests := map[string]bool{
+		"yarn.lock":          true,
+		"Cargo.lock":         false,
+		"generated/a.go":     true,
+		"generated/keep.go":  false,
+		"internal/config.go": false,
+	}
+	for path, want := range tests {
+		if got := m.Match(path); got != want {
+			t.Errorf("Match(%q) = %v, 期望 %v", path, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatalf("加载失败: %v", err)
+	}
+	if !m.Match("go.sum") || !m.Match("web/package-lock.json") {
+		t.Error("没有 .aicommitignore 时应使用内置的锁文件规则")
+	}
+
+	content := "# 生成的代码\n*.pb.go\nvendor/\n\n!go.sum\n"
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0644); err != nil {
+		t.Fatalf("写入 %s 失败: %v", FileName, err)
+	}
+	m, err = Load(dir)
+	if err != nil {
+		t.Fatalf("加载失败: %v", err)
+	}
+
+	kept, ignored := m.Split([]string{"main.go", "go.sum", "api/user.pb.go", "vendor/x/y.go", "yarn.lock"})
+	if len(kept) != 2 || kept[0] != "main.go" || kept[1] != "go.sum" {
+		t.Errorf("期望保留 main.go 和 go.sum, 实际=%v", kept)
+	}
+	if len(ignored) != 3 {
+		t.Errorf("期望忽略 3 个文件, 实际=%v", ignored)
+	}
+}
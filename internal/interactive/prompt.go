@@ -157,6 +157,70 @@ func printBox(title string, lines []string, width int) {
 	fmt.Println("└" + strings.Repeat("─", width+2) + "┘")
 }
 
+// IsTerminal 判断标准输出是否为终端
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// IsInputTerminal 判断标准输入是否为终端，不是终端时无法读取按键
+func IsInputTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// StreamBox 在生成过程中实时显示模型输出
+// 输出完成后调用 Close 擦除，再由 ShowCommitMessage 绘制最终的消息框
+type StreamBox struct {
+	termWidth int // 终端宽度，用于计算自动换行占用的行数
+	col       int // 当前光标所在列
+	rows      int // 标题行之后已占用的行数
+}
+
+// NewStreamBox 绘制流式输出框的顶部边框
+func NewStreamBox(title string) *StreamBox {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+
+	b := &StreamBox{termWidth: width}
+	fmt.Println()
+	fmt.Println("┌─" + title + strings.Repeat("─", 60-displayWidth(title)))
+	fmt.Print("│ ")
+	b.col = 2
+	return b
+}
+
+// Write 追加模型输出的一段文本
+func (b *StreamBox) Write(token string) {
+	var out strings.Builder
+	for _, r := range token {
+		switch r {
+		case '\r':
+			continue
+		case '\n':
+			out.WriteString("\n│ ")
+			b.rows++
+			b.col = 2
+			continue
+		}
+
+		w := runewidth.RuneWidth(r)
+		if b.col+w > b.termWidth {
+			// 终端自动换行
+			b.rows++
+			b.col = 0
+		}
+		out.WriteRune(r)
+		b.col += w
+	}
+	fmt.Print(out.String())
+}
+
+// Close 擦除流式输出框（包括前面的空行）
+func (b *StreamBox) Close() {
+	fmt.Printf("\r\033[%dA\033[J", b.rows+2)
+}
+
 // ShowFileStatusAndSelect 显示文件状态并让用户选择操作
 // 返回: "use-staged", "select-files", "stage-all", "cancel"
 func ShowFileStatusAndSelect(staged, modified, untracked []string) (string, error) {
@@ -483,72 +547,21 @@ const (
 	ActionAccept     CommitAction = "accept"
 	ActionEdit       CommitAction = "edit"
 	ActionRegenerate CommitAction = "regenerate"
+	ActionRefine     CommitAction = "refine"
 	ActionCancel     CommitAction = "cancel"
 )
 
-// ShowCommitMessage 显示提交消息并让用户选择操作
-func ShowCommitMessage(title, body string) (CommitAction, error) {
-	// 准备要显示的行
-	var lines []string
-	maxWidth := 60 // default width
-
-	// Add title (bold)
-	titleLine := fmt.Sprintf("\033[1m%s\033[0m", title)
-	lines = append(lines, titleLine)
-	if w := displayWidth(titleLine) + 2; w > maxWidth {
-		maxWidth = w
-	}
-
-	// Add body if present
-	if body != "" {
-		lines = append(lines, "")
-		for _, line := range strings.Split(body, "\n") {
-			lines = append(lines, line)
-			if w := displayWidth(line) + 2; w > maxWidth {
-				maxWidth = w
-			}
-		}
-	}
+// ShowCommitMessage 显示提交消息并让用户选择操作，warnings 为提交信息不符合规范的提示
+func ShowCommitMessage(title, body string, warnings []string) (CommitAction, error) {
+	lines, maxWidth := messageLines(title, body)
 
 	// Display the box
 	fmt.Println()
 	printBox("✔ 生成的提交消息", lines, maxWidth)
-
-	// 准备选项框
-	type Option struct {
-		Key       string
-		Label     string
-		IsDefault bool
-	}
-
-	options := []Option{
-		{Key: "a", Label: "接受并提交", IsDefault: true},
-		{Key: "e", Label: "编辑后提交", IsDefault: false},
-		{Key: "r", Label: "重新生成", IsDefault: false},
-		{Key: "c", Label: "取消", IsDefault: false},
-	}
-
-	var optionLines []string
-	optionMaxWidth := 40
-
-	for _, opt := range options {
-		var line string
-		if opt.IsDefault {
-			line = fmt.Sprintf("  \033[1m[%s]\033[0m %s \033[90m(默认)\033[0m", opt.Key, opt.Label)
-		} else {
-			line = fmt.Sprintf("  [%s] %s", opt.Key, opt.Label)
-		}
-		optionLines = append(optionLines, line)
-		if w := displayWidth(line) + 2; w > optionMaxWidth {
-			optionMaxWidth = w
-		}
-	}
-
-	optionLines = append(optionLines, "")
-	optionLines = append(optionLines, "\033[90m提示: 输入字母或直接按回车选择默认选项\033[0m")
+	printWarnings(warnings)
 
 	fmt.Println()
-	printBox("请选择操作", optionLines, optionMaxWidth)
+	printActionBox(nil)
 	fmt.Print("\n请按键选择: ")
 
 	// 读取单个字符
@@ -572,6 +585,8 @@ func ShowCommitMessage(title, body string) (CommitAction, error) {
 		return ActionEdit, nil
 	case 'r', 'R':
 		return ActionRegenerate, nil
+	case 'f', 'F':
+		return ActionRefine, nil
 	case 'c', 'C', 3: // 3 是 Ctrl+C
 		return ActionCancel, nil
 	default:
@@ -581,6 +596,7 @@ func ShowCommitMessage(title, body string) (CommitAction, error) {
 			"接受并提交",
 			"编辑后提交",
 			"重新生成",
+			"根据反馈修改",
 			"取消",
 		}
 
@@ -601,12 +617,250 @@ func ShowCommitMessage(title, body string) (CommitAction, error) {
 			return ActionEdit, nil
 		case 2:
 			return ActionRegenerate, nil
+		case 3:
+			return ActionRefine, nil
 		default:
 			return ActionCancel, nil
 		}
 	}
 }
 
+// MaxCandidates 最多同时展示的候选提交消息数
+const MaxCandidates = 9
+
+// Candidate 候选提交消息
+type Candidate struct {
+	Title string
+	Body  string
+	// Warnings 提交信息不符合规范的提示
+	Warnings []string
+}
+
+// ShowCandidates 显示候选提交消息列表和当前选中的消息，并让用户选择操作
+// 按数字键或 ↑/↓ 切换候选，返回用户选择的操作和选中的候选下标
+func ShowCandidates(candidates []Candidate, selected int) (CommitAction, int, error) {
+	if len(candidates) == 0 {
+		return ActionCancel, 0, fmt.Errorf("没有候选提交消息")
+	}
+	selected = max(0, min(selected, len(candidates)-1))
+
+	rendered := 0
+	for {
+		// 清除上一次的输出后重新绘制
+		if rendered > 0 {
+			fmt.Printf("\033[%dA\r\033[J", rendered)
+		}
+		rendered = renderCandidates(candidates, selected)
+
+		key, err := readKey()
+		if err != nil {
+			return ActionCancel, selected, err
+		}
+
+		switch {
+		case len(key) == 3 && key[0] == 27 && key[1] == '[' && key[2] == 'A':
+			selected = (selected + len(candidates) - 1) % len(candidates)
+			continue
+		case len(key) == 3 && key[0] == 27 && key[1] == '[' && key[2] == 'B':
+			selected = (selected + 1) % len(candidates)
+			continue
+		case len(key) != 1:
+			continue
+		}
+
+		switch k := key[0]; {
+		case k >= '1' && k <= '9':
+			if i := int(k - '1'); i < len(candidates) {
+				selected = i
+			}
+		case k == 13 || k == 10:
+			fmt.Println("(回车)")
+			return ActionAccept, selected, nil
+		case k == 'a' || k == 'A':
+			fmt.Println(string(k))
+			return ActionAccept, selected, nil
+		case k == 'e' || k == 'E':
+			fmt.Println(string(k))
+			return ActionEdit, selected, nil
+		case k == 'r' || k == 'R':
+			fmt.Println(string(k))
+			return ActionRegenerate, selected, nil
+		case k == 'f' || k == 'F':
+			fmt.Println(string(k))
+			return ActionRefine, selected, nil
+		case k == 'c' || k == 'C' || k == 3: // 3 是 Ctrl+C
+			fmt.Println(string(k))
+			return ActionCancel, selected, nil
+		}
+		// 其他按键忽略，重新绘制
+	}
+}
+
+// renderCandidates 绘制候选列表、选中的消息和操作选项，返回光标上移到起始位置所需的行数
+func renderCandidates(candidates []Candidate, selected int) int {
+	var listLines []string
+	listWidth := 60
+	for i, c := range candidates {
+		line := fmt.Sprintf("  %d. %s", i+1, c.Title)
+		if i == selected {
+			line = fmt.Sprintf("\033[36m❯ %d. %s\033[0m", i+1, c.Title)
+		}
+		listLines = append(listLines, line)
+		if w := displayWidth(line) + 2; w > listWidth {
+			listWidth = w
+		}
+	}
+
+	lines, maxWidth := messageLines(candidates[selected].Title, candidates[selected].Body)
+	hints := []string{fmt.Sprintf("提示: 按 1-%d 或 ↑/↓ 切换候选", len(candidates))}
+
+	fmt.Println()
+	printBox(fmt.Sprintf("✔ 生成了 %d 条候选提交消息", len(candidates)), listLines, listWidth)
+	fmt.Println()
+	printBox(fmt.Sprintf("候选 %d", selected+1), lines, maxWidth)
+	warningLines := printWarnings(candidates[selected].Warnings)
+	fmt.Println()
+	actionLines := printActionBox(hints)
+	fmt.Print("\n请按键选择: ")
+
+	// 空行 + 三个框（各含上下边框）+ 规范提示 + 框之间的空行 + 提示前的空行
+	return 1 + (len(listLines) + 2) + 1 + (len(lines) + 2) + warningLines + 1 + actionLines + 1
+}
+
+// readKey 以原始模式读取一次按键，方向键等转义序列会一并返回
+func readKey() ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = term.Restore(fd, oldState) }()
+
+	buf := make([]byte, 3)
+	n, err := os.Stdin.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// messageLines 把提交消息转换为消息框中的行，返回行和所需宽度
+func messageLines(title, body string) ([]string, int) {
+	var lines []string
+	maxWidth := 60 // default width
+
+	// Add title (bold)
+	titleLine := fmt.Sprintf("\033[1m%s\033[0m", title)
+	lines = append(lines, titleLine)
+	if w := displayWidth(titleLine) + 2; w > maxWidth {
+		maxWidth = w
+	}
+
+	// Add body if present
+	if body != "" {
+		lines = append(lines, "")
+		for _, line := range strings.Split(body, "\n") {
+			lines = append(lines, line)
+			if w := displayWidth(line) + 2; w > maxWidth {
+				maxWidth = w
+			}
+		}
+	}
+	return lines, maxWidth
+}
+
+// printWarnings 在消息框下方打印不符合规范的提示，返回打印的行数
+func printWarnings(warnings []string) int {
+	for _, w := range warnings {
+		fmt.Printf("\033[33m⚠ %s\033[0m\n", w)
+	}
+	return len(warnings)
+}
+
+// printActionBox 打印操作选项框，hints 为追加在默认提示之前的提示行，返回打印的行数
+func printActionBox(hints []string) int {
+	type Option struct {
+		Key       string
+		Label     string
+		IsDefault bool
+	}
+
+	options := []Option{
+		{Key: "a", Label: "接受并提交", IsDefault: true},
+		{Key: "e", Label: "编辑后提交", IsDefault: false},
+		{Key: "r", Label: "重新生成", IsDefault: false},
+		{Key: "f", Label: "根据反馈修改", IsDefault: false},
+		{Key: "c", Label: "取消", IsDefault: false},
+	}
+
+	var optionLines []string
+	optionMaxWidth := 40
+
+	for _, opt := range options {
+		var line string
+		if opt.IsDefault {
+			line = fmt.Sprintf("  \033[1m[%s]\033[0m %s \033[90m(默认)\033[0m", opt.Key, opt.Label)
+		} else {
+			line = fmt.Sprintf("  [%s] %s", opt.Key, opt.Label)
+		}
+		optionLines = append(optionLines, line)
+		if w := displayWidth(line) + 2; w > optionMaxWidth {
+			optionMaxWidth = w
+		}
+	}
+
+	optionLines = append(optionLines, "")
+	for _, hint := range hints {
+		optionLines = append(optionLines, "\033[90m"+hint+"\033[0m")
+	}
+	optionLines = append(optionLines, "\033[90m提示: 输入字母或直接按回车选择默认选项\033[0m")
+
+	printBox("请选择操作", optionLines, optionMaxWidth)
+	return len(optionLines) + 2
+}
+
+// RewriteRow 改写提交信息时一个提交的新旧标题
+type RewriteRow struct {
+	Commit string
+	Old    string
+	New    string
+	// Unchanged 保持原来的提交信息（如 fixup! 提交）
+	Unchanged bool
+}
+
+// ShowRewriteTable 以左右两列对照显示各提交原来的和新生成的标题，超出终端宽度的部分截断
+func ShowRewriteTable(rows []RewriteRow) {
+	width := 120
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		width = w
+	}
+	// 每行为 "哈希  原标题 │ 新标题"，两列平分剩余宽度
+	column := max((width-7-2-3)/2, 20)
+
+	fmt.Printf("\033[1m%s  %s │ %s\033[0m\n", padWidth("提交", 7), padWidth("原提交信息", column), "新提交信息")
+	fmt.Println(strings.Repeat("─", 7+2+column+1) + "┼" + strings.Repeat("─", column+1))
+	for _, row := range rows {
+		updated := truncateWidth(row.New, column)
+		if row.Unchanged {
+			updated = "\033[90m(保持不变)\033[0m"
+		}
+		fmt.Printf("\033[33m%-7s\033[0m  %s │ %s\n", row.Commit, padWidth(truncateWidth(row.Old, column), column), updated)
+	}
+}
+
+// truncateWidth 把字符串截断到不超过 width 列，截断时以 "…" 结尾
+func truncateWidth(s string, width int) string {
+	if runewidth.StringWidth(s) <= width {
+		return s
+	}
+	return runewidth.Truncate(s, width, "…")
+}
+
+// padWidth 在字符串末尾补空格到 width 列
+func padWidth(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-displayWidth(s), 0))
+}
+
 // EditMessage 编辑消息 (使用 $EDITOR 或默认 vi)
 func EditMessage(content string) (string, error) {
 	editor := os.Getenv("EDITOR")
@@ -646,6 +900,22 @@ func EditMessage(content string) (string, error) {
 	return strings.TrimSpace(string(edited)), nil
 }
 
+// PromptFeedback 读取用户对提交消息的修改意见
+func PromptFeedback() (string, error) {
+	prompt := promptui.Prompt{
+		Label: "修改意见 (如: 更简短, scope 改为 auth)",
+	}
+
+	feedback, err := prompt.Run()
+	if err != nil {
+		if err == promptui.ErrInterrupt || err == promptui.ErrEOF {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(feedback), nil
+}
+
 // PromptConfirm 确认提示
 func PromptConfirm(message string) (bool, error) {
 	prompt := promptui.Prompt{
@@ -0,0 +1,309 @@
+// Package lint 按 Conventional Commits 规范检查提交信息，并修复可以自动修复的问题
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxSubjectLength 主题的最大字符数
+	DefaultMaxSubjectLength = 50
+	// DefaultMaxBodyLineLength 正文每行的最大字符数
+	DefaultMaxBodyLineLength = 72
+)
+
+// Severity 问题的严重程度
+type Severity string
+
+const (
+	// SeverityError 不符合规范，生成时会要求模型修正
+	SeverityError Severity = "error"
+	// SeverityWarning 建议修改
+	SeverityWarning Severity = "warning"
+)
+
+// 规则名称
+const (
+	RuleHeaderFormat         = "header-format"
+	RuleTypeCase             = "type-case"
+	RuleTypeEnum             = "type-enum"
+	RuleScopeFormat          = "scope-format"
+	RuleSubjectEmpty         = "subject-empty"
+	RuleSubjectMaxLength     = "subject-max-length"
+	RuleSubjectFullStop      = "subject-full-stop"
+	RuleBodyLeadingBlank     = "body-leading-blank"
+	RuleBodyMaxLineLength    = "body-max-line-length"
+	RuleFooterBreakingChange = "footer-breaking-change"
+)
+
+// Issue 一处不符合规范的地方
+type Issue struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Line 所在行号，从 1 开始
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("第 %d 行: %s [%s]", i.Line, i.Message, i.Rule)
+}
+
+// Rules 检查使用的规则参数
+type Rules struct {
+	// Types 允许的提交类型，为空时不检查类型
+	Types             []string
+	MaxSubjectLength  int
+	MaxBodyLineLength int
+}
+
+// DefaultRules 返回使用默认长度限制的规则
+func DefaultRules(types []string) Rules {
+	return Rules{
+		Types:             types,
+		MaxSubjectLength:  DefaultMaxSubjectLength,
+		MaxBodyLineLength: DefaultMaxBodyLineLength,
+	}
+}
+
+// HasErrors 判断是否包含错误级别的问题
+func HasErrors(issues []Issue) bool {
+	return slices.ContainsFunc(issues, func(i Issue) bool { return i.Severity == SeverityError })
+}
+
+var (
+	// typePattern 提交类型只能由小写字母组成
+	typePattern = regexp.MustCompile(`^[a-z]+$`)
+	// headerPattern 解析 "<type>(<scope>)!: <subject>"，分隔符单独分组以便检查冒号后的空格
+	headerPattern = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?(:[ \t]*)(.*)$`)
+	// breakingPattern 匹配各种写法的破坏性变更脚注
+	breakingPattern = regexp.MustCompile(`^(?i:breaking[ _-]?changes?|重大变更|重大變更)[ \t]*[:：][ \t]*(.*)$`)
+	// validBreakingPattern 规范的破坏性变更脚注
+	validBreakingPattern = regexp.MustCompile(`^BREAKING[ -]CHANGE: \S`)
+	// noBreakingPattern 表示没有破坏性变更的写法，如 "Breaking changes: none"，不是脚注
+	noBreakingPattern = regexp.MustCompile(`^(?i:none|no|n/?a|无|没有|無|沒有)[.。]?$`)
+)
+
+// ValidType 判断提交类型是否有效（只由小写字母组成）
+func ValidType(typ string) bool {
+	return typePattern.MatchString(typ)
+}
+
+// Header 标题 "<type>(<scope>)!: <subject>" 的各部分
+type Header struct {
+	Type     string
+	Scope    string
+	Breaking bool
+	Subject  string
+}
+
+// ParseHeader 解析标题，类型转为小写，范围和主题去掉两端空白
+// 标题不符合约定格式或主题为空时返回 false，格式细节（大小写、冒号后的空格等）由 Check 检查
+func ParseHeader(header string) (Header, bool) {
+	match := headerPattern.FindStringSubmatch(header)
+	if match == nil || strings.TrimSpace(match[5]) == "" {
+		return Header{}, false
+	}
+	return Header{
+		Type:     strings.ToLower(match[1]),
+		Scope:    strings.TrimSpace(match[2]),
+		Breaking: match[3] == "!",
+		Subject:  strings.TrimSpace(match[5]),
+	}, true
+}
+
+// generatedPattern git 自动生成的标题：合并、回滚以及 rebase --autosquash 使用的 fixup!/squash!/amend!
+var generatedPattern = regexp.MustCompile(`^(?:Merge |Revert "|(?:fixup|squash|amend)! )`)
+
+// Ignored 判断提交信息是否由 git 自动生成，这类提交不要求符合规范
+func Ignored(message string) bool {
+	return generatedPattern.MatchString(message)
+}
+
+// Check 检查提交信息，返回所有问题
+func Check(message string, rules Rules) []Issue {
+	_, issues := lint(message, rules, false)
+	return issues
+}
+
+// Fix 修复可以自动修复的问题（类型大小写、末尾句号、缺少空行、正文换行、破坏性变更脚注写法等），
+// 返回修复后的提交信息和仍然存在的问题
+func Fix(message string, rules Rules) (string, []Issue) {
+	fixed, _ := lint(message, rules, true)
+	return fixed, Check(fixed, rules)
+}
+
+// lint 逐条检查规则，fix 为 true 时就地修复并返回修复后的提交信息
+func lint(message string, rules Rules, fix bool) (string, []Issue) {
+	lines := strings.Split(strings.TrimRight(message, "\n"), "\n")
+	if fix {
+		for i, line := range lines {
+			lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+		}
+	}
+
+	var issues []Issue
+	report := func(rule string, severity Severity, line int, format string, args ...any) {
+		issues = append(issues, Issue{Rule: rule, Severity: severity, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	lines[0] = lintHeader(lines[0], rules, fix, report)
+
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		if fix {
+			lines = slices.Insert(lines, 1, "")
+		} else {
+			report(RuleBodyLeadingBlank, SeverityError, 2, "标题和正文之间需要空一行")
+		}
+	}
+
+	// 脚注只在最后一段，正文中类似脚注的句子保持不变
+	footer := 1
+	for i := len(lines) - 1; i > 0; i-- {
+		if strings.TrimSpace(lines[i]) == "" {
+			footer = i + 1
+			break
+		}
+	}
+
+	var body []string
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		lineNo := i + 1
+
+		if match := breakingPattern.FindStringSubmatch(line); i >= footer && match != nil &&
+			!validBreakingPattern.MatchString(line) && !noBreakingPattern.MatchString(strings.TrimSpace(match[1])) {
+			switch {
+			case strings.TrimSpace(match[1]) == "":
+				report(RuleFooterBreakingChange, SeverityError, lineNo, "BREAKING CHANGE 脚注需要说明变更内容")
+			case fix:
+				line = "BREAKING CHANGE: " + strings.TrimSpace(match[1])
+			default:
+				report(RuleFooterBreakingChange, SeverityError, lineNo, "破坏性变更脚注应写为 \"BREAKING CHANGE: <说明>\"")
+			}
+		}
+
+		if limit := rules.MaxBodyLineLength; limit > 0 && utf8.RuneCountInString(line) > limit && wrappable(line) {
+			if fix {
+				body = append(body, wrap(line, limit)...)
+				continue
+			}
+			report(RuleBodyMaxLineLength, SeverityWarning, lineNo, "正文每行不应超过 %d 个字符（当前 %d）", limit, utf8.RuneCountInString(line))
+		}
+		body = append(body, line)
+	}
+
+	return strings.Join(append(lines[:1], body...), "\n"), issues
+}
+
+// lintHeader 检查标题行，fix 为 true 时返回修复后的标题
+func lintHeader(header string, rules Rules, fix bool, report func(rule string, severity Severity, line int, format string, args ...any)) string {
+	match := headerPattern.FindStringSubmatch(header)
+	if match == nil {
+		report(RuleHeaderFormat, SeverityError, 1, "标题应为 \"<type>(<scope>): <subject>\" 格式")
+		return header
+	}
+	typ, scope, breaking, separator, subject := match[1], match[2], match[3], match[4], match[5]
+	hasScope := strings.Contains(header[:len(typ)+1], "(")
+
+	if typ != strings.ToLower(typ) {
+		if fix {
+			typ = strings.ToLower(typ)
+		} else {
+			report(RuleTypeCase, SeverityError, 1, "提交类型应为小写: %s", typ)
+		}
+	}
+	if len(rules.Types) > 0 && !slices.Contains(rules.Types, strings.ToLower(typ)) {
+		report(RuleTypeEnum, SeverityError, 1, "不支持的提交类型 %q（可选: %s）", typ, strings.Join(rules.Types, ", "))
+	}
+
+	if hasScope {
+		trimmed := strings.TrimSpace(scope)
+		switch {
+		case trimmed == "" && fix:
+			hasScope = false
+		case trimmed == "":
+			report(RuleScopeFormat, SeverityError, 1, "范围不能为空，没有范围时去掉括号")
+		case strings.IndexFunc(trimmed, unicode.IsSpace) >= 0:
+			report(RuleScopeFormat, SeverityError, 1, "范围不能包含空白字符: %q", scope)
+		case trimmed != scope && fix:
+			scope = trimmed
+		case trimmed != scope:
+			report(RuleScopeFormat, SeverityError, 1, "范围两端不能有空格: %q", scope)
+		}
+	}
+
+	if separator != ": " {
+		if fix {
+			separator = ": "
+		} else {
+			report(RuleHeaderFormat, SeverityError, 1, "冒号后需要一个空格")
+		}
+	}
+
+	subject = strings.TrimSpace(subject)
+	if fix {
+		subject = strings.TrimRightFunc(subject, func(r rune) bool { return r == '.' || r == '。' || unicode.IsSpace(r) })
+	}
+	switch {
+	case subject == "":
+		report(RuleSubjectEmpty, SeverityError, 1, "主题不能为空")
+	case strings.HasSuffix(subject, ".") || strings.HasSuffix(subject, "。"):
+		report(RuleSubjectFullStop, SeverityError, 1, "主题末尾不应有句号")
+	}
+	if limit := rules.MaxSubjectLength; limit > 0 && utf8.RuneCountInString(subject) > limit {
+		report(RuleSubjectMaxLength, SeverityError, 1, "主题不应超过 %d 个字符（当前 %d）", limit, utf8.RuneCountInString(subject))
+	}
+
+	if !fix {
+		return header
+	}
+	var b strings.Builder
+	b.WriteString(typ)
+	if hasScope {
+		b.WriteString("(" + scope + ")")
+	}
+	b.WriteString(breaking + separator + subject)
+	return b.String()
+}
+
+// wrappable 判断过长的行能否按空格换行：缩进的代码块和没有空格的长链接不换行
+func wrappable(line string) bool {
+	if strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+		return false
+	}
+	return strings.Contains(strings.TrimSpace(line), " ")
+}
+
+// listMarkerPattern 列表项的前缀，换行后的续行与列表内容对齐
+var listMarkerPattern = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+
+// wrap 按空格把一行拆分为不超过 width 个字符的多行，单个词超过 width 时单独成行
+func wrap(line string, width int) []string {
+	indent := ""
+	if marker := listMarkerPattern.FindString(line); marker != "" {
+		indent = strings.Repeat(" ", utf8.RuneCountInString(marker))
+	} else {
+		indent = line[:len(line)-len(strings.TrimLeft(line, " "))]
+	}
+
+	var lines []string
+	current := ""
+	for i, word := range strings.Fields(line) {
+		switch {
+		case i == 0:
+			current = line[:len(line)-len(strings.TrimLeft(line, " "))] + word
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= width:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = indent + word
+		}
+	}
+	return append(lines, current)
+}
@@ -0,0 +1,156 @@
+package lint
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testRules = DefaultRules([]string{"feat", "fix", "docs", "chore"})
+
+// rulesOf 返回问题对应的规则名称
+func rulesOf(issues []Issue) []string {
+	var rules []string
+	for _, i := range issues {
+		rules = append(rules, i.Rule)
+	}
+	return rules
+}
+
+func TestCheck_Valid(t *testing.T) {
+	messages := []string{
+		"feat: add login",
+		"fix(auth)!: reject expired tokens\n\nTokens past their expiry are now rejected.\n\nBREAKING CHANGE: clients must refresh tokens",
+		"docs(认证): 更新说明\n\n- 补充配置示例",
+		"chore: bump deps\n\nBREAKING-CHANGE: drop go 1.20",
+	}
+	for _, msg := range messages {
+		if issues := Check(msg, testRules); len(issues) != 0 {
+			t.Errorf("%q 应通过检查, 实际=%v", msg, issues)
+		}
+	}
+}
+
+func TestCheck_Violations(t *testing.T) {
+	testCases := []struct {
+		message string
+		rule    string
+		line    int
+	}{
+		{"add login", RuleHeaderFormat, 1},
+		{"feat:add login", RuleHeaderFormat, 1},
+		{"Feat: add login", RuleTypeCase, 1},
+		{"perf: faster", RuleTypeEnum, 1},
+		{"feat(): add login", RuleScopeFormat, 1},
+		{"feat(user auth): add login", RuleScopeFormat, 1},
+		{"feat: ", RuleSubjectEmpty, 1},
+		{"feat: add login.", RuleSubjectFullStop, 1},
+		{"feat: 添加登录。", RuleSubjectFullStop, 1},
+		{"feat: " + strings.Repeat("x", 51), RuleSubjectMaxLength, 1},
+		{"feat: add login\nbody right after header", RuleBodyLeadingBlank, 2},
+		{"feat: add login\n\n" + strings.Repeat("word ", 20), RuleBodyMaxLineLength, 3},
+		{"feat: add login\n\nBreaking change: new header", RuleFooterBreakingChange, 3},
+		{"feat: add login\n\nBREAKING CHANGE:", RuleFooterBreakingChange, 3},
+	}
+	for _, tc := range testCases {
+		issues := Check(tc.message, testRules)
+		found := false
+		for _, i := range issues {
+			if i.Rule == tc.rule && i.Line == tc.line {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%q 应违反 %s (第 %d 行), 实际=%v", tc.message, tc.rule, tc.line, issues)
+		}
+	}
+}
+
+func TestCheck_Severity(t *testing.T) {
+	issues := Check("feat: add login\n\n"+strings.Repeat("word ", 20), testRules)
+	if HasErrors(issues) {
+		t.Errorf("正文过长只应是警告, 实际=%v", issues)
+	}
+	if !HasErrors(Check("feat: add login.", testRules)) {
+		t.Error("末尾句号应是错误")
+	}
+}
+
+func TestFix(t *testing.T) {
+	message := "Feat( api ):add login.\n" +
+		"- add the login endpoint and wire it into the router so that clients can authenticate\n" +
+		"\n" +
+		"breaking changes: clients must send credentials"
+
+	fixed, issues := Fix(message, testRules)
+	want := "feat(api): add login\n" +
+		"\n" +
+		"- add the login endpoint and wire it into the router so that clients can\n" +
+		"  authenticate\n" +
+		"\n" +
+		"BREAKING CHANGE: clients must send credentials"
+	if fixed != want {
+		t.Errorf("修复结果错误:\n%s\n期望:\n%s", fixed, want)
+	}
+	if len(issues) != 0 {
+		t.Errorf("修复后不应有问题, 实际=%v", issues)
+	}
+}
+
+func TestFix_BreakingChangeOnlyInFooter(t *testing.T) {
+	messages := []string{
+		"feat: add login\n\nBreaking changes: none",
+		"feat: add login\n\n重大变更：无",
+		"feat: add login\n\nBreaking changes: the session cookie is renamed\nwhich only affects internal tools.\n\nRefs: #12",
+	}
+	for _, message := range messages {
+		fixed, issues := Fix(message, testRules)
+		if fixed != message {
+			t.Errorf("不是破坏性变更脚注的行不应修改:\n%s\n实际:\n%s", message, fixed)
+		}
+		if slices.Contains(rulesOf(issues), RuleFooterBreakingChange) {
+			t.Errorf("%q 不应报告 %s", message, RuleFooterBreakingChange)
+		}
+	}
+}
+
+func TestFix_Unfixable(t *testing.T) {
+	_, issues := Fix("perf: "+strings.Repeat("x", 60), testRules)
+	rules := strings.Join(rulesOf(issues), ",")
+	if rules != RuleTypeEnum+","+RuleSubjectMaxLength {
+		t.Errorf("无法自动修复的问题应保留, 实际=%s", rules)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	header, ok := ParseHeader("Feat( api )!:add login")
+	want := Header{Type: "feat", Scope: "api", Breaking: true, Subject: "add login"}
+	if !ok || header != want {
+		t.Errorf("解析结果错误: %+v, 期望 %+v", header, want)
+	}
+	for _, title := range []string{"add login", "feat:", "feat(api): "} {
+		if _, ok := ParseHeader(title); ok {
+			t.Errorf("%q 不应解析成功", title)
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	ignored := []string{
+		"Merge branch 'main' into feature",
+		"Merge pull request #12 from user/branch",
+		"Revert \"feat: add login\"",
+		"fixup! feat: add login",
+		"squash! fix: typo",
+	}
+	for _, msg := range ignored {
+		if !Ignored(msg) {
+			t.Errorf("%q 应跳过检查", msg)
+		}
+	}
+	for _, msg := range []string{"feat: merge configs", "Mergeable: yes", "revert: drop cache"} {
+		if Ignored(msg) {
+			t.Errorf("%q 不应跳过检查", msg)
+		}
+	}
+}
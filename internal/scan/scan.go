@@ -0,0 +1,368 @@
+// Package scan 在把 diff 发送给 AI 之前检测其中的密钥和凭据
+package scan
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SimonGino/aicommit/internal/ignore"
+)
+
+// AllowMarker 行内包含该标记时跳过检测，用于测试数据等误报
+const AllowMarker = "aicommit:allow"
+
+// Mode 检测到密钥时的处理方式
+type Mode string
+
+const (
+	// ModeRedact 替换为占位符后继续（默认）
+	ModeRedact Mode = "redact"
+	// ModeAbort 中止并列出密钥所在的文件和行号
+	ModeAbort Mode = "abort"
+	// ModeOff 不检测
+	ModeOff Mode = "off"
+)
+
+// ParseMode 解析处理方式，空字符串表示默认的 ModeRedact
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeRedact:
+		return ModeRedact, nil
+	case ModeAbort, ModeOff:
+		return Mode(s), nil
+	default:
+		return "", fmt.Errorf("不支持的密钥检测模式: %s（可选: redact, abort, off）", s)
+	}
+}
+
+// Finding 一处疑似密钥
+type Finding struct {
+	File string
+	Line int // 所在行号：删除行为旧文件行号，其余为新文件行号
+	Rule string
+	// Secret 打码后的内容，只保留前 4 个字符
+	Secret string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d  %s  %s", f.File, f.Line, f.Rule, f.Secret)
+}
+
+// rule 检测规则，secret 为匹配中密钥所在的分组（0 表示整个匹配）
+type rule struct {
+	id     string
+	re     *regexp.Regexp
+	secret int
+}
+
+var rules = []rule{
+	{id: "aws-access-key", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{id: "aws-secret-key", re: regexp.MustCompile(`(?i)aws.{0,20}(?:secret|private).{0,20}?[:=]\s*['"]?([A-Za-z0-9/+]{40})\b`), secret: 1},
+	{id: "github-token", re: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{id: "slack-token", re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{id: "openai-api-key", re: regexp.MustCompile(`\bsk-(?:proj-|ant-)?[A-Za-z0-9_-]{20,}`)},
+	{id: "google-api-key", re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{id: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+}
+
+var (
+	pemBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	pemEnd   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+
+	// entropyCandidate 引号中或赋值号后的长字符串
+	entropyCandidate = regexp.MustCompile(`(?:['"` + "`" + `]|[:=]\s*)([A-Za-z0-9+/=_\-]{20,})`)
+)
+
+// minEntropy 判定为高熵字符串的香农熵阈值（比特/字符）
+const minEntropy = 4.0
+
+// Allowlist 白名单
+type Allowlist struct {
+	// Paths 跳过检测的文件，使用 gitignore 语法（如 "testdata/"、"*.golden"）
+	Paths []string
+	// Patterns 匹配密钥内容的正则表达式，命中时视为误报
+	Patterns []string
+}
+
+// Scanner 密钥检测器
+type Scanner struct {
+	paths    *ignore.Matcher
+	patterns []*regexp.Regexp
+}
+
+// New 创建检测器
+func New(allow Allowlist) (*Scanner, error) {
+	s := &Scanner{paths: ignore.New(allow.Paths)}
+	for _, p := range allow.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("无效的白名单正则 %q: %w", p, err)
+		}
+		s.patterns = append(s.patterns, re)
+	}
+	return s, nil
+}
+
+// ParseAllowlist 解析配置中的白名单，"path:" 前缀表示文件 glob，其余为正则表达式
+func ParseAllowlist(entries []string) Allowlist {
+	var allow Allowlist
+	for _, entry := range entries {
+		if p, ok := strings.CutPrefix(entry, "path:"); ok {
+			allow.Paths = append(allow.Paths, p)
+		} else {
+			allow.Patterns = append(allow.Patterns, entry)
+		}
+	}
+	return allow
+}
+
+// Scan 检测 diff 中的疑似密钥
+func (s *Scanner) Scan(diff string) []Finding {
+	_, findings := s.process(diff, false)
+	return findings
+}
+
+// Redact 把 diff 中的疑似密钥替换为 [REDACTED:规则] 占位符
+func (s *Scanner) Redact(diff string) (string, []Finding) {
+	return s.process(diff, true)
+}
+
+// process 逐行扫描 diff 中会发送给模型的内容（新增、删除和上下文行）
+func (s *Scanner) process(diff string, redact bool) (string, []Finding) {
+	var out strings.Builder
+	var findings []Finding
+
+	file := ""
+	skipFile := false
+	inHunk := false
+	inPEM := false
+	oldLine, newLine := 0, 0
+
+	lines := strings.SplitAfter(diff, "\n")
+	for _, raw := range lines {
+		line := strings.TrimRight(raw, "\n")
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			file = parseDiffPath(line)
+			skipFile = s.allowedPath(file)
+			inHunk, inPEM = false, false
+			out.WriteString(raw)
+			continue
+		case !inHunk && strings.HasPrefix(line, "+++ "):
+			if p := strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/"); p != "/dev/null" {
+				file = p
+				skipFile = s.allowedPath(file)
+			}
+			out.WriteString(raw)
+			continue
+		case strings.HasPrefix(line, "@@"):
+			oldLine, newLine = parseHunkHeader(line)
+			inHunk = true
+			out.WriteString(raw)
+			continue
+		}
+
+		if !inHunk || line == "" || !strings.ContainsAny(line[:1], "+- ") {
+			out.WriteString(raw)
+			continue
+		}
+
+		prefix, content := line[:1], line[1:]
+		lineNo := newLine
+		if prefix == "-" {
+			lineNo = oldLine
+			oldLine++
+		} else {
+			if prefix == " " {
+				oldLine++
+			}
+			newLine++
+		}
+
+		if skipFile || strings.Contains(content, AllowMarker) {
+			out.WriteString(raw)
+			continue
+		}
+
+		// PEM 私钥块跨多行，整块替换为一个占位符
+		if inPEM {
+			if pemEnd.MatchString(content) {
+				inPEM = false
+			}
+			if !redact {
+				out.WriteString(raw)
+			}
+			continue
+		}
+		if loc := pemBegin.FindStringIndex(content); loc != nil {
+			findings = append(findings, Finding{File: file, Line: lineNo, Rule: "private-key", Secret: mask(content[loc[0]:loc[1]])})
+			inPEM = !pemEnd.MatchString(content[loc[1]:])
+			if redact {
+				out.WriteString(prefix + content[:loc[0]] + "[REDACTED:private-key]" + lineEnding(raw))
+			} else {
+				out.WriteString(raw)
+			}
+			continue
+		}
+
+		replaced, lineFindings := s.scanLine(content)
+		for i := range lineFindings {
+			lineFindings[i].File = file
+			lineFindings[i].Line = lineNo
+		}
+		findings = append(findings, lineFindings...)
+		if redact && len(lineFindings) > 0 {
+			out.WriteString(prefix + replaced + lineEnding(raw))
+		} else {
+			out.WriteString(raw)
+		}
+	}
+
+	return out.String(), findings
+}
+
+// scanLine 检测单行内容，返回替换后的内容和命中的规则
+func (s *Scanner) scanLine(content string) (string, []Finding) {
+	var findings []Finding
+
+	for _, r := range rules {
+		content = replaceGroup(r.re, content, r.secret, func(secret string) (string, bool) {
+			if s.allowedSecret(secret) {
+				return "", false
+			}
+			findings = append(findings, Finding{Rule: r.id, Secret: mask(secret)})
+			return "[REDACTED:" + r.id + "]", true
+		})
+	}
+
+	content = replaceGroup(entropyCandidate, content, 1, func(secret string) (string, bool) {
+		if !looksRandom(secret) || s.allowedSecret(secret) {
+			return "", false
+		}
+		findings = append(findings, Finding{Rule: "high-entropy-string", Secret: mask(secret)})
+		return "[REDACTED:high-entropy-string]", true
+	})
+
+	return content, findings
+}
+
+func (s *Scanner) allowedPath(file string) bool {
+	return s.paths.Match(file)
+}
+
+func (s *Scanner) allowedSecret(secret string) bool {
+	for _, re := range s.patterns {
+		if re.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceGroup 对 re 的每个匹配取第 group 个分组调用 fn，fn 返回 true 时替换该分组
+func replaceGroup(re *regexp.Regexp, s string, group int, fn func(string) (string, bool)) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 {
+			continue
+		}
+		replacement, ok := fn(s[start:end])
+		if !ok {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(replacement)
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// looksRandom 判断字符串是否像随机生成的密钥：同时包含大小写字母和数字，且熵足够高
+func looksRandom(s string) bool {
+	var upper, lower, digit bool
+	for _, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit && entropy(s) >= minEntropy
+}
+
+// entropy 计算香农熵（比特/字符）
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len([]rune(s)))
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// mask 只保留前 4 个字符
+func mask(secret string) string {
+	if len(secret) <= 4 {
+		return "****"
+	}
+	return secret[:4] + "****"
+}
+
+// parseDiffPath 从 "diff --git a/x b/x" 中取出文件路径
+func parseDiffPath(line string) string {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if i := strings.Index(rest, " b/"); i >= 0 {
+		return rest[i+3:]
+	}
+	return rest
+}
+
+// parseHunkHeader 解析 "@@ -a,b +c,d @@" 中旧文件和新文件的起始行号
+func parseHunkHeader(line string) (oldStart, newStart int) {
+	fields := strings.Fields(line)
+	for _, f := range fields[1:] {
+		switch {
+		case strings.HasPrefix(f, "-"):
+			oldStart = atoiPrefix(f[1:])
+		case strings.HasPrefix(f, "+"):
+			newStart = atoiPrefix(f[1:])
+			return
+		}
+	}
+	return
+}
+
+func atoiPrefix(s string) int {
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		s = s[:i]
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func lineEnding(raw string) string {
+	if strings.HasSuffix(raw, "\n") {
+		return "\n"
+	}
+	return ""
+}
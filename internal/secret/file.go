@@ -0,0 +1,230 @@
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+const (
+	// secretsFileName 加密文件名，与 config.json 位于同一目录
+	secretsFileName = "secrets.enc"
+	secretsVersion  = 1
+
+	// scrypt 参数，见 https://pkg.go.dev/golang.org/x/crypto/scrypt
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// FilePath 返回加密文件路径
+func FilePath(dir string) string {
+	return filepath.Join(dir, secretsFileName)
+}
+
+// encryptedFile 加密文件的格式：scrypt 派生密钥，NaCl secretbox 加密 JSON 编码的密钥表
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// fileStore 使用口令加密的文件保存密钥
+// 首次读写时询问口令并解密，之后在内存中缓存
+type fileStore struct {
+	path       string
+	passphrase func(confirm bool) (string, error)
+
+	loaded  bool
+	salt    []byte
+	key     *[32]byte
+	secrets map[string]string
+}
+
+func newFileStore(path string, passphrase func(confirm bool) (string, error)) *fileStore {
+	return &fileStore{path: path, passphrase: passphrase}
+}
+
+func (s *fileStore) Get(account string) (string, error) {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		// 文件不存在时无需询问口令
+		return "", ErrNotFound
+	}
+	if err := s.load(); err != nil {
+		return "", err
+	}
+
+	value, ok := s.secrets[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *fileStore) Set(account, value string) error {
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.secrets[account] = value
+	return s.write()
+}
+
+func (s *fileStore) Delete(account string) error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.secrets[account]; !ok {
+		return nil
+	}
+	delete(s.secrets, account)
+	return s.write()
+}
+
+// load 读取并解密文件，文件不存在时使用新口令初始化
+func (s *fileStore) load() error {
+	if s.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		passphrase, err := s.passphrase(true)
+		if err != nil {
+			return err
+		}
+		s.salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, s.salt); err != nil {
+			return fmt.Errorf("生成随机数失败: %w", err)
+		}
+		if s.key, err = deriveKey(passphrase, s.salt); err != nil {
+			return err
+		}
+		s.secrets = map[string]string{}
+		s.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析密钥文件 %s 失败: %w", s.path, err)
+	}
+	if file.Version != secretsVersion || len(file.Nonce) != 24 {
+		return fmt.Errorf("不支持的密钥文件格式: %s", s.path)
+	}
+
+	passphrase, err := s.passphrase(false)
+	if err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], file.Nonce)
+	plaintext, ok := secretbox.Open(nil, file.Ciphertext, &nonce, key)
+	if !ok {
+		return errors.New("口令错误或密钥文件已损坏")
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return fmt.Errorf("解析密钥文件内容失败: %w", err)
+	}
+
+	s.salt, s.key, s.secrets, s.loaded = file.Salt, key, secrets, true
+	return nil
+}
+
+// write 使用新的随机 nonce 加密并写入文件
+func (s *fileStore) write() error {
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return fmt.Errorf("序列化密钥失败: %w", err)
+	}
+
+	var nonce [24]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return fmt.Errorf("生成随机数失败: %w", err)
+	}
+
+	data, err := json.MarshalIndent(encryptedFile{
+		Version:    secretsVersion,
+		Salt:       s.salt,
+		Nonce:      nonce[:],
+		Ciphertext: secretbox.Seal(nil, plaintext, &nonce, s.key),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化密钥文件失败: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("保存密钥文件失败: %w", err)
+	}
+	return nil
+}
+
+func deriveKey(passphrase string, salt []byte) (*[32]byte, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, fmt.Errorf("派生加密密钥失败: %w", err)
+	}
+	var key [32]byte
+	copy(key[:], derived)
+	return &key, nil
+}
+
+// passphraseFromEnvOrTerminal 从环境变量读取口令，未设置时在终端中输入
+// confirm 为 true 时（新建文件）要求输入两次
+func passphraseFromEnvOrTerminal(confirm bool) (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("密钥文件需要口令，请设置环境变量 %s", PassphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, "请输入密钥文件口令: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("读取口令失败: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return "", errors.New("口令不能为空")
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "请再次输入口令: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("读取口令失败: %w", err)
+		}
+		if string(again) != string(passphrase) {
+			return "", errors.New("两次输入的口令不一致")
+		}
+	}
+	return string(passphrase), nil
+}
@@ -0,0 +1,46 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService 系统钥匙串中的服务名
+const keyringService = "aicommit"
+
+// keyringStore 使用系统钥匙串保存密钥
+type keyringStore struct{}
+
+func (keyringStore) Get(account string) (string, error) {
+	value, err := keyring.Get(keyringService, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取系统钥匙串失败: %w", err)
+	}
+	return value, nil
+}
+
+func (keyringStore) Set(account, value string) error {
+	if err := keyring.Set(keyringService, account, value); err != nil {
+		return fmt.Errorf("写入系统钥匙串失败: %w", err)
+	}
+	return nil
+}
+
+func (keyringStore) Delete(account string) error {
+	err := keyring.Delete(keyringService, account)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("删除系统钥匙串中的密钥失败: %w", err)
+	}
+	return nil
+}
+
+// keyringAvailable 探测系统钥匙串是否可用（如 Linux 上未运行 Secret Service 时不可用）
+func keyringAvailable() bool {
+	_, err := keyring.Get(keyringService, "__probe__")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
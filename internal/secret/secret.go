@@ -0,0 +1,86 @@
+// Package secret 提供 API Key 等敏感信息的存储后端
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Backend 密钥存储后端
+type Backend string
+
+const (
+	// BackendKeyring 系统钥匙串（macOS Keychain、Windows 凭据管理器、Linux Secret Service）
+	BackendKeyring Backend = "keyring"
+	// BackendFile 使用口令加密的本地文件，适用于没有 Secret Service 的 Linux 服务器
+	BackendFile Backend = "file"
+	// BackendPlain 明文保存在配置文件中
+	BackendPlain Backend = "plain"
+)
+
+// PassphraseEnv 加密文件口令的环境变量，未设置时在终端中交互输入
+const PassphraseEnv = "AICOMMIT_SECRET_PASSPHRASE"
+
+// ErrNotFound 密钥不存在
+var ErrNotFound = errors.New("未找到密钥")
+
+// Store 密钥存储，account 用于区分不同档案和提供商的密钥
+type Store interface {
+	Get(account string) (string, error)
+	Set(account, value string) error
+	Delete(account string) error
+}
+
+// Backends 返回所有可选的后端名称
+func Backends() []Backend {
+	return []Backend{BackendKeyring, BackendFile, BackendPlain}
+}
+
+// ParseBackend 解析后端名称
+func ParseBackend(name string) (Backend, error) {
+	for _, b := range Backends() {
+		if string(b) == name {
+			return b, nil
+		}
+	}
+	return "", fmt.Errorf("不支持的密钥存储: %s（可选: keyring, file, plain）", name)
+}
+
+// Label 返回后端的显示名称
+func (b Backend) Label() string {
+	switch b {
+	case BackendKeyring:
+		return "系统钥匙串"
+	case BackendFile:
+		return "加密文件"
+	default:
+		return "配置文件（明文）"
+	}
+}
+
+// Open 打开指定后端，dir 为加密文件所在目录
+// BackendPlain 没有独立的存储，返回 nil
+func Open(backend Backend, dir string) (Store, error) {
+	switch backend {
+	case BackendKeyring:
+		return keyringStore{}, nil
+	case BackendFile:
+		return newFileStore(FilePath(dir), passphraseFromEnvOrTerminal), nil
+	case BackendPlain:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥存储: %s", backend)
+	}
+}
+
+// Detect 选择可用的后端：优先系统钥匙串，其次设置了口令环境变量时使用加密文件
+func Detect() Backend {
+	if keyringAvailable() {
+		return BackendKeyring
+	}
+	if os.Getenv(PassphraseEnv) != "" {
+		return BackendFile
+	}
+	return BackendPlain
+}
@@ -0,0 +1,104 @@
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func fixedPassphrase(p string) func(bool) (string, error) {
+	return func(bool) (string, error) { return p, nil }
+}
+
+func TestFileStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	store := newFileStore(path, fixedPassphrase("correct horse"))
+	if err := store.Set("default", "sk-secret-value"); err != nil {
+		t.Fatalf("写入密钥失败: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取密钥文件失败: %v", err)
+	}
+	if strings.Contains(string(data), "sk-secret-value") {
+		t.Error("密钥文件中不应出现明文")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("期望文件权限 0600, 实际=%v", info.Mode().Perm())
+	}
+
+	reopened := newFileStore(path, fixedPassphrase("correct horse"))
+	value, err := reopened.Get("default")
+	if err != nil || value != "sk-secret-value" {
+		t.Errorf("期望读取到密钥, 实际=%q, err=%v", value, err)
+	}
+	if _, err := reopened.Get("work"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("期望 ErrNotFound, 实际=%v", err)
+	}
+
+	if err := reopened.Delete("default"); err != nil {
+		t.Fatalf("删除密钥失败: %v", err)
+	}
+	if _, err := newFileStore(path, fixedPassphrase("correct horse")).Get("default"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后期望 ErrNotFound, 实际=%v", err)
+	}
+}
+
+func TestFileStore_WrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	if err := newFileStore(path, fixedPassphrase("right")).Set("default", "sk-1"); err != nil {
+		t.Fatalf("写入密钥失败: %v", err)
+	}
+
+	if _, err := newFileStore(path, fixedPassphrase("wrong")).Get("default"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("口令错误时应返回解密错误, 实际=%v", err)
+	}
+}
+
+func TestFileStore_MissingFileSkipsPassphrase(t *testing.T) {
+	store := newFileStore(filepath.Join(t.TempDir(), "secrets.enc"), func(bool) (string, error) {
+		t.Error("文件不存在时不应询问口令")
+		return "", errors.New("unexpected")
+	})
+	if _, err := store.Get("default"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("期望 ErrNotFound, 实际=%v", err)
+	}
+}
+
+func TestKeyringStore(t *testing.T) {
+	keyring.MockInit()
+
+	store, err := Open(BackendKeyring, "")
+	if err != nil {
+		t.Fatalf("打开钥匙串失败: %v", err)
+	}
+	if _, err := store.Get("default"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("期望 ErrNotFound, 实际=%v", err)
+	}
+	if err := store.Set("default", "sk-1"); err != nil {
+		t.Fatalf("写入钥匙串失败: %v", err)
+	}
+	if value, _ := store.Get("default"); value != "sk-1" {
+		t.Errorf("期望读取到 sk-1, 实际=%q", value)
+	}
+	if err := store.Delete("missing"); err != nil {
+		t.Errorf("删除不存在的密钥不应报错: %v", err)
+	}
+}
+
+func TestParseBackend(t *testing.T) {
+	for _, name := range []string{"keyring", "file", "plain"} {
+		if _, err := ParseBackend(name); err != nil {
+			t.Errorf("ParseBackend(%q) 失败: %v", name, err)
+		}
+	}
+	if _, err := ParseBackend("vault"); err == nil {
+		t.Error("期望不支持的后端返回错误")
+	}
+}